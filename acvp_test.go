@@ -0,0 +1,208 @@
+package kyber
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// The ACVP FIPS 203 sample vectors of NIST, see testdata/README.md.
+
+type acvpHex []byte
+
+func (h *acvpHex) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	*h = v
+	return nil
+}
+
+func acvp_read(t *testing.T, dir string, name string, v interface{}) {
+	f, err := os.Open(filepath.Join("testdata", dir, name+".json.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func acvp_parameters(t *testing.T, name string) *Parameters {
+	switch name {
+	case "ML-KEM-512":
+		return NewMLKEMParameters(2)
+	case "ML-KEM-768":
+		return NewMLKEMParameters(3)
+	case "ML-KEM-1024":
+		return NewMLKEMParameters(4)
+	}
+	t.Fatalf("unknown parameter set %q", name)
+	return nil
+}
+
+func Test_ACVP_KeyGen(t *testing.T) {
+	var prompt struct {
+		TestGroups []struct {
+			TgID         int    `json:"tgId"`
+			ParameterSet string `json:"parameterSet"`
+			Tests        []struct {
+				TcID int     `json:"tcId"`
+				Z    acvpHex `json:"z"`
+				D    acvpHex `json:"d"`
+			} `json:"tests"`
+		} `json:"testGroups"`
+	}
+	var results struct {
+		TestGroups []struct {
+			Tests []struct {
+				TcID int     `json:"tcId"`
+				Ek   acvpHex `json:"ek"`
+				Dk   acvpHex `json:"dk"`
+			} `json:"tests"`
+		} `json:"testGroups"`
+	}
+	acvp_read(t, "ML-KEM-keyGen-FIPS203", "prompt", &prompt)
+	acvp_read(t, "ML-KEM-keyGen-FIPS203", "expectedResults", &results)
+
+	type keys struct{ ek, dk []byte }
+	expected := make(map[int]keys)
+	for _, g := range results.TestGroups {
+		for _, tc := range g.Tests {
+			expected[tc.TcID] = keys{tc.Ek, tc.Dk}
+		}
+	}
+
+	count := make(map[string]int)
+	for _, g := range prompt.TestGroups {
+		params := acvp_parameters(t, g.ParameterSet)
+		for _, tc := range g.Tests {
+			want, ok := expected[tc.TcID]
+			if !ok {
+				t.Fatalf("tcId %d: no expected result", tc.TcID)
+			}
+			pk, sk, err := KeypairDerand(params, tc.D, tc.Z)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(pk, want.ek) {
+				t.Errorf("%s tcId %d: ek mismatch", g.ParameterSet, tc.TcID)
+			}
+			if !bytes.Equal(sk, want.dk) {
+				t.Errorf("%s tcId %d: dk mismatch", g.ParameterSet, tc.TcID)
+			}
+			count[g.ParameterSet]++
+		}
+	}
+	for _, name := range []string{"ML-KEM-512", "ML-KEM-768", "ML-KEM-1024"} {
+		if count[name] == 0 {
+			t.Errorf("%s: no keyGen vectors", name)
+		}
+	}
+}
+
+func Test_ACVP_EncapDecap(t *testing.T) {
+	var prompt struct {
+		TestGroups []struct {
+			TgID         int     `json:"tgId"`
+			ParameterSet string  `json:"parameterSet"`
+			Function     string  `json:"function"`
+			Dk           acvpHex `json:"dk"`
+			Tests        []struct {
+				TcID int     `json:"tcId"`
+				Ek   acvpHex `json:"ek"`
+				M    acvpHex `json:"m"`
+				C    acvpHex `json:"c"`
+			} `json:"tests"`
+		} `json:"testGroups"`
+	}
+	var results struct {
+		TestGroups []struct {
+			Tests []struct {
+				TcID int     `json:"tcId"`
+				C    acvpHex `json:"c"`
+				K    acvpHex `json:"k"`
+			} `json:"tests"`
+		} `json:"testGroups"`
+	}
+	acvp_read(t, "ML-KEM-encapDecap-FIPS203", "prompt", &prompt)
+	acvp_read(t, "ML-KEM-encapDecap-FIPS203", "expectedResults", &results)
+
+	type result struct{ c, k []byte }
+	expected := make(map[int]result)
+	for _, g := range results.TestGroups {
+		for _, tc := range g.Tests {
+			expected[tc.TcID] = result{tc.C, tc.K}
+		}
+	}
+
+	encaps := make(map[string]int)
+	decaps := make(map[string]int)
+	rejections := make(map[string]int)
+	for _, g := range prompt.TestGroups {
+		params := acvp_parameters(t, g.ParameterSet)
+		for _, tc := range g.Tests {
+			want, ok := expected[tc.TcID]
+			if !ok {
+				t.Fatalf("tcId %d: no expected result", tc.TcID)
+			}
+			switch g.Function {
+			case "encapsulation":
+				ct, ss, err := EncapsulateDerand(params, tc.Ek, tc.M)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(ct, want.c) || !bytes.Equal(ss, want.k) {
+					t.Errorf("%s tcId %d: encapsulation mismatch", g.ParameterSet, tc.TcID)
+				}
+				encaps[g.ParameterSet]++
+			case "decapsulation":
+				ss, err := Crypto_kem_dec_checked(params, tc.C, g.Dk)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(ss, want.k) {
+					t.Errorf("%s tcId %d: decapsulation mismatch", g.ParameterSet, tc.TcID)
+				}
+				// Implicit rejection returns J(z||c), z being the last
+				// KYBER_SYMBYTES bytes of the secret key
+				j := make([]byte, KYBER_SSBYTES)
+				h := sha3.NewShake256()
+				h.Write(g.Dk[len(g.Dk)-KYBER_SYMBYTES:])
+				h.Write(tc.C)
+				h.Read(j)
+				if bytes.Equal(ss, j) {
+					rejections[g.ParameterSet]++
+				}
+				decaps[g.ParameterSet]++
+			default:
+				t.Fatalf("tgId %d: unknown function %q", g.TgID, g.Function)
+			}
+		}
+	}
+	for _, name := range []string{"ML-KEM-512", "ML-KEM-768", "ML-KEM-1024"} {
+		if encaps[name] == 0 || decaps[name] == 0 {
+			t.Errorf("%s: missing encapsulation or decapsulation vectors", name)
+		}
+		if rejections[name] == 0 || rejections[name] == decaps[name] {
+			t.Errorf("%s: %d of %d decapsulation vectors are implicit rejections",
+				name, rejections[name], decaps[name])
+		}
+	}
+}
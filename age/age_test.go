@@ -0,0 +1,132 @@
+package age
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBech32(t *testing.T) {
+	// valid and invalid strings from BIP 173
+	for _, s := range []string{
+		"A12UEL5L",
+		"a12uel5l",
+		"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs",
+		"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw",
+		"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w",
+	} {
+		hrp, values, err := bech32Decode(s)
+		if err != nil {
+			// these carry 5-bit data that need not regroup into bytes
+			if !strings.Contains(err.Error(), "padding") {
+				t.Errorf("%s: %v", s, err)
+			}
+			continue
+		}
+		got, err := bech32Encode(hrp, values)
+		if err != nil || got != strings.ToLower(s) {
+			t.Errorf("%s: re-encoded as %s, %v", s, got, err)
+		}
+	}
+	for _, s := range []string{
+		"pzry9x0s0muk",
+		"1pzry9x0s0muk",
+		"x1b4n0q5v",
+		"li1dgmt3",
+		"A1G7SGD8",
+		"a12UEL5L",
+		"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxx",
+	} {
+		if _, _, err := bech32Decode(s); err == nil {
+			t.Errorf("%s: accepted", s)
+		}
+	}
+
+	data := bytes.Repeat([]byte{0x5a}, 2000)
+	s, err := bech32Encode("test", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hrp, got, err := bech32Decode(s); err != nil || hrp != "test" || !bytes.Equal(got, data) {
+		t.Errorf("long string round trip: %v", err)
+	}
+}
+
+func TestHybrid(t *testing.T) {
+	id, err := GenerateHybridIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	idStr, rStr := id.String(), id.Recipient().String()
+	if !strings.HasPrefix(idStr, "AGE-SECRET-KEY-PQ-1") || !strings.HasPrefix(rStr, "age1pq1") {
+		t.Fatalf("unexpected encodings %.24s..., %.12s...", idStr, rStr)
+	}
+	id2, err := ParseHybridIdentity(idStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := ParseHybridRecipient(rStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.PublicKey().Equal(id.Recipient().PublicKey()) || id2.String() != idStr {
+		t.Error("parsed keys differ")
+	}
+
+	fileKey := []byte("YELLOW SUBMARINE")
+	stanzas, labels, err := r.WrapWithLabels(fileKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stanzas) != 1 || stanzas[0].Type != StanzaType || len(labels) != 1 || labels[0] != "postquantum" {
+		t.Fatalf("unexpected stanzas %v, labels %v", stanzas, labels)
+	}
+
+	// another recipient's stanza and a foreign stanza type are skipped
+	other, _ := GenerateHybridIdentity()
+	otherStanzas, _ := other.Recipient().Wrap([]byte("0123456789abcdef"))
+	header := []*Stanza{{Type: "X25519", Args: []string{"x"}, Body: make([]byte, 32)}, otherStanzas[0], stanzas[0]}
+	got, err := id2.Unwrap(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, fileKey) {
+		t.Error("unwrapped file key differs")
+	}
+	if _, err := id.Unwrap(otherStanzas); !errors.Is(err, ErrIncorrectIdentity) {
+		t.Errorf("wrong identity: got %v", err)
+	}
+
+	bad := *stanzas[0]
+	bad.Body = append([]byte{}, bad.Body...)
+	bad.Body[0] ^= 1
+	if _, err := id.Unwrap([]*Stanza{&bad}); !errors.Is(err, ErrIncorrectIdentity) {
+		t.Errorf("tampered body: got %v", err)
+	}
+	for _, s := range []*Stanza{
+		{Type: StanzaType, Body: stanzas[0].Body},
+		{Type: StanzaType, Args: []string{stanzas[0].Args[0][1:]}, Body: stanzas[0].Body},
+		{Type: StanzaType, Args: stanzas[0].Args, Body: stanzas[0].Body[1:]},
+	} {
+		if _, err := id.Unwrap([]*Stanza{s}); err == nil || errors.Is(err, ErrIncorrectIdentity) {
+			t.Errorf("malformed stanza: got %v", err)
+		}
+	}
+	if _, err := r.Wrap(make([]byte, 32)); err == nil {
+		t.Error("32-byte file key accepted")
+	}
+
+	// upper case is valid Bech32
+	if _, err := ParseHybridRecipient(strings.ToUpper(rStr)); err != nil {
+		t.Errorf("upper case recipient: %v", err)
+	}
+	for _, s := range []string{rStr[:len(rStr)-1] + "q", idStr, "age1qqqqqqqq"} {
+		if _, err := ParseHybridRecipient(s); err == nil {
+			t.Errorf("recipient %.16s... accepted", s)
+		}
+	}
+	if _, err := ParseHybridIdentity(rStr); err == nil {
+		t.Error("recipient parsed as identity")
+	}
+}
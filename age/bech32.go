@@ -0,0 +1,137 @@
+package age
+
+import (
+	"errors"
+	"strings"
+)
+
+// Bech32 (BIP 173) without the 90 character limit, as used by age for
+// keys that do not fit it
+
+const charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+var generator = [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+func polymod(values []byte) uint32 {
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+func hrpExpand(hrp string) []byte {
+	h := []byte(strings.ToLower(hrp))
+	out := make([]byte, 0, 2*len(h)+1)
+	for _, c := range h {
+		out = append(out, c>>5)
+	}
+	out = append(out, 0)
+	for _, c := range h {
+		out = append(out, c&31)
+	}
+	return out
+}
+
+// convertBits regroups data from frombits-bit to tobits-bit groups
+func convertBits(data []byte, frombits, tobits byte, pad bool) ([]byte, error) {
+	var out []byte
+	acc, bits := uint32(0), byte(0)
+	maxv := byte(1)<<tobits - 1
+	for _, b := range data {
+		if b>>frombits != 0 {
+			return nil, errors.New("bech32: invalid data range")
+		}
+		acc = acc<<frombits | uint32(b)
+		bits += frombits
+		for bits >= tobits {
+			bits -= tobits
+			out = append(out, byte(acc>>bits)&maxv)
+		}
+	}
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(tobits-bits))&maxv)
+		}
+	} else if bits >= frombits || byte(acc<<(tobits-bits))&maxv != 0 {
+		return nil, errors.New("bech32: invalid padding")
+	}
+	return out, nil
+}
+
+// bech32Encode encodes data with the human readable part hrp, keeping
+// the case of hrp for the whole string
+func bech32Encode(hrp string, data []byte) (string, error) {
+	values, err := convertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	if len(hrp) < 1 {
+		return "", errors.New("bech32: empty human readable part")
+	}
+	for _, c := range []byte(hrp) {
+		if c < 33 || c > 126 {
+			return "", errors.New("bech32: invalid human readable part")
+		}
+	}
+	if strings.ToUpper(hrp) != hrp && strings.ToLower(hrp) != hrp {
+		return "", errors.New("bech32: mixed case human readable part")
+	}
+	lower := strings.ToLower(hrp) == hrp
+
+	values = append(values, make([]byte, 6)...)
+	mod := polymod(append(hrpExpand(hrp), values...)) ^ 1
+	for i := 0; i < 6; i++ {
+		values[len(values)-6+i] = byte(mod>>uint(5*(5-i))) & 31
+	}
+	var sb strings.Builder
+	sb.WriteString(strings.ToLower(hrp))
+	sb.WriteByte('1')
+	for _, v := range values {
+		sb.WriteByte(charset[v])
+	}
+	if lower {
+		return sb.String(), nil
+	}
+	return strings.ToUpper(sb.String()), nil
+}
+
+// bech32Decode returns the lower case human readable part and data of s
+func bech32Decode(s string) (string, []byte, error) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, errors.New("bech32: mixed case")
+	}
+	s = strings.ToLower(s)
+	pos := strings.LastIndexByte(s, '1')
+	if pos < 1 || pos+7 > len(s) {
+		return "", nil, errors.New("bech32: invalid separator position")
+	}
+	hrp := s[:pos]
+	for _, c := range []byte(hrp) {
+		if c < 33 || c > 126 {
+			return "", nil, errors.New("bech32: invalid human readable part")
+		}
+	}
+	values := make([]byte, 0, len(s)-pos-1)
+	for _, c := range []byte(s[pos+1:]) {
+		v := strings.IndexByte(charset, c)
+		if v < 0 {
+			return "", nil, errors.New("bech32: invalid character")
+		}
+		values = append(values, byte(v))
+	}
+	if polymod(append(hrpExpand(hrp), values...)) != 1 {
+		return "", nil, errors.New("bech32: invalid checksum")
+	}
+	data, err := convertBits(values[:len(values)-6], 5, 8, false)
+	if err != nil {
+		return "", nil, err
+	}
+	return hrp, data, nil
+}
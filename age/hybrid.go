@@ -0,0 +1,208 @@
+package age
+
+import (
+	"crypto/rand"
+	"strings"
+
+	"github.com/depressi0n/kyber-go/hpke"
+	"github.com/depressi0n/kyber-go/xwing"
+)
+
+const (
+	StanzaType  = "mlkem768x25519"
+	StanzaLabel = "age-encryption.org/mlkem768x25519"
+
+	recipientHRP = "age1pq"
+	identityHRP  = "AGE-SECRET-KEY-PQ-"
+)
+
+var suite = hpke.NewSuite(hpke.XWing(), hpke.HKDFSHA256(), hpke.ChaCha20Poly1305())
+
+// HybridRecipient encrypts file keys to an X-Wing public key
+type HybridRecipient struct {
+	pub *xwing.PublicKey
+}
+
+// HybridIdentity decrypts file keys wrapped to its X-Wing public key
+type HybridIdentity struct {
+	priv *xwing.PrivateKey
+}
+
+var (
+	_ Recipient           = (*HybridRecipient)(nil)
+	_ RecipientWithLabels = (*HybridRecipient)(nil)
+	_ Identity            = (*HybridIdentity)(nil)
+)
+
+// NewHybridRecipient returns a recipient for pub
+func NewHybridRecipient(pub *xwing.PublicKey) *HybridRecipient {
+	return &HybridRecipient{pub: pub}
+}
+
+/*************************************************
+* Name:        ParseHybridRecipient
+*
+* Description: Parses an "age1pq1..." Bech32 recipient
+*
+* Arguments:   - s string: input recipient string
+*
+* Returns      - r *HybridRecipient: output recipient
+*              - err error: non-nil on bad input
+**************************************************/
+func ParseHybridRecipient(s string) (*HybridRecipient, error) {
+	hrp, data, err := bech32Decode(s)
+	if err != nil {
+		return nil, errorf("malformed recipient %q: %v", s, err)
+	}
+	if hrp != recipientHRP {
+		return nil, errorf("malformed recipient %q: invalid type %q", s, hrp)
+	}
+	pub, err := xwing.ParsePublicKey(data)
+	if err != nil {
+		return nil, errorf("malformed recipient %q: %v", s, err)
+	}
+	return NewHybridRecipient(pub), nil
+}
+
+// String returns the Bech32 "age1pq1..." encoding of r
+func (r *HybridRecipient) String() string {
+	s, _ := bech32Encode(recipientHRP, r.pub.Bytes())
+	return s
+}
+
+// PublicKey returns the X-Wing public key of r
+func (r *HybridRecipient) PublicKey() *xwing.PublicKey {
+	return r.pub
+}
+
+/*************************************************
+* Name:        Wrap
+*
+* Description: Wraps fileKey in one mlkem768x25519 stanza
+*
+* Arguments:   - fileKey []byte: input 16-byte file key
+*
+* Returns      - s []*Stanza: output stanzas
+*              - err error: non-nil on failure
+**************************************************/
+func (r *HybridRecipient) Wrap(fileKey []byte) ([]*Stanza, error) {
+	s, _, err := r.WrapWithLabels(fileKey)
+	return s, err
+}
+
+// WrapWithLabels is Wrap returning the "postquantum" label, so age refuses
+// to mix r with recipients that would weaken the file to classical security
+func (r *HybridRecipient) WrapWithLabels(fileKey []byte) ([]*Stanza, []string, error) {
+	if len(fileKey) != fileKeySize {
+		return nil, nil, errorf("invalid file key length %d", len(fileKey))
+	}
+	enc, sender, err := suite.SetupBaseS(hpke.NewXWingPublicKey(r.pub), []byte(StanzaLabel), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := sender.Seal(nil, fileKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	s := &Stanza{
+		Type: StanzaType,
+		Args: []string{b64.EncodeToString(enc)},
+		Body: body,
+	}
+	return []*Stanza{s}, []string{"postquantum"}, nil
+}
+
+/*************************************************
+* Name:        GenerateHybridIdentity
+*
+* Description: Generates a fresh identity from crypto/rand
+*
+* Returns      - i *HybridIdentity: output identity
+*              - err error: non-nil on failure
+**************************************************/
+func GenerateHybridIdentity() (*HybridIdentity, error) {
+	priv, err := xwing.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return NewHybridIdentity(priv), nil
+}
+
+// NewHybridIdentity returns an identity for priv
+func NewHybridIdentity(priv *xwing.PrivateKey) *HybridIdentity {
+	return &HybridIdentity{priv: priv}
+}
+
+/*************************************************
+* Name:        ParseHybridIdentity
+*
+* Description: Parses an "AGE-SECRET-KEY-PQ-1..." Bech32 identity
+*
+* Arguments:   - s string: input identity string
+*
+* Returns      - i *HybridIdentity: output identity
+*              - err error: non-nil on bad input
+**************************************************/
+func ParseHybridIdentity(s string) (*HybridIdentity, error) {
+	hrp, data, err := bech32Decode(s)
+	if err != nil {
+		return nil, errorf("malformed secret key: %v", err)
+	}
+	if hrp != strings.ToLower(identityHRP) {
+		return nil, errorf("malformed secret key: unknown type %q", hrp)
+	}
+	priv, err := xwing.NewPrivateKeyFromSeed(data)
+	if err != nil {
+		return nil, errorf("malformed secret key: %v", err)
+	}
+	return NewHybridIdentity(priv), nil
+}
+
+// String returns the Bech32 "AGE-SECRET-KEY-PQ-1..." encoding of i
+func (i *HybridIdentity) String() string {
+	s, _ := bech32Encode(identityHRP, i.priv.Bytes())
+	return s
+}
+
+// Recipient returns the recipient of i
+func (i *HybridIdentity) Recipient() *HybridRecipient {
+	return NewHybridRecipient(i.priv.PublicKey())
+}
+
+/*************************************************
+* Name:        Unwrap
+*
+* Description: Returns the file key of the first mlkem768x25519 stanza
+*              that decrypts under i
+*
+* Arguments:   - stanzas []*Stanza: input header stanzas
+*
+* Returns      - fileKey []byte: output 16-byte file key
+*              - err error: ErrIncorrectIdentity if no stanza is for i,
+*                non-nil on a malformed mlkem768x25519 stanza
+**************************************************/
+func (i *HybridIdentity) Unwrap(stanzas []*Stanza) ([]byte, error) {
+	skR := hpke.NewXWingPrivateKey(i.priv)
+	for _, s := range stanzas {
+		if s.Type != StanzaType {
+			continue
+		}
+		if len(s.Args) != 1 {
+			return nil, errorf("invalid %s stanza", StanzaType)
+		}
+		enc, err := b64.DecodeString(s.Args[0])
+		if err != nil || len(enc) != xwing.CiphertextSize {
+			return nil, errorf("invalid %s stanza", StanzaType)
+		}
+		if len(s.Body) != fileKeySize+16 {
+			return nil, errorf("invalid %s stanza", StanzaType)
+		}
+		fileKey, err := suite.Open(enc, skR, []byte(StanzaLabel), nil, s.Body)
+		if err != nil {
+			// the stanza is for another recipient
+			continue
+		}
+		return fileKey, nil
+	}
+	return nil, ErrIncorrectIdentity
+}
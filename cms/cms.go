@@ -0,0 +1,287 @@
+// Package cms encodes and decodes the KEMRecipientInfo of RFC 9629 for
+// ML-KEM recipients of CMS EnvelopedData, with the algorithms of
+// draft-ietf-lamps-cms-kyber: HKDF-SHA256 as KDF, AES-128 key wrap for
+// ML-KEM-512 and AES-256 key wrap for ML-KEM-768 and ML-KEM-1024.
+//
+// The KEMRecipientInfo is carried in the ori alternative of
+// RecipientInfo:
+//
+//	RecipientInfo ::= CHOICE { ..., ori [4] OtherRecipientInfo }
+//	OtherRecipientInfo ::= SEQUENCE { oriType id-ori-kem, oriValue KEMRecipientInfo }
+//
+// and the key-encryption key is HKDF-SHA256(ss, salt = empty,
+// info = DER(CMSORIforKEMOtherInfo), kekLength). Only the recipient part
+// is handled here; the rest of the EnvelopedData is left to the caller.
+package cms
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	kyber "github.com/depressi0n/kyber-go"
+	"golang.org/x/crypto/hkdf"
+)
+
+var (
+	// id-ori-kem (RFC 9629)
+	OIDOriKEM = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 13, 3}
+	// id-alg-hkdf-with-sha256 (RFC 8619)
+	OIDHKDFSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 3, 28}
+	// id-aes128-wrap, id-aes256-wrap (RFC 3565)
+	OIDAES128Wrap = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 5}
+	OIDAES256Wrap = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 45}
+	// id-alg-ml-kem-* (FIPS 203)
+	OIDMLKEM512  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 4, 1}
+	OIDMLKEM768  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 4, 2}
+	OIDMLKEM1024 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 4, 3}
+)
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("cms: unsupported algorithm")
+	ErrMalformed            = errors.New("cms: malformed KEMRecipientInfo")
+)
+
+// KEMRecipientInfo is the RFC 9629 structure
+//
+//	KEMRecipientInfo ::= SEQUENCE {
+//	  version CMSVersion,  -- always set to 0
+//	  rid RecipientIdentifier,
+//	  kem KEMAlgorithmIdentifier,
+//	  kemct OCTET STRING,
+//	  kdf KeyDerivationAlgorithmIdentifier,
+//	  kekLength INTEGER (1..65535),
+//	  ukm [0] EXPLICIT UserKeyingMaterial OPTIONAL,
+//	  wrap KeyEncryptionAlgorithmIdentifier,
+//	  encryptedKey EncryptedKey }
+type KEMRecipientInfo struct {
+	Version      int
+	RID          asn1.RawValue // see SubjectKeyIdentifier and IssuerAndSerialNumber
+	KEM          pkix.AlgorithmIdentifier
+	KEMCT        []byte
+	KDF          pkix.AlgorithmIdentifier
+	KEKLength    int
+	UKM          []byte `asn1:"optional,explicit,tag:0"`
+	Wrap         pkix.AlgorithmIdentifier
+	EncryptedKey []byte
+}
+
+type otherRecipientInfo struct {
+	Type  asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+// CMSORIforKEMOtherInfo, the HKDF info
+type kemOtherInfo struct {
+	Wrap      pkix.AlgorithmIdentifier
+	KEKLength int
+	UKM       []byte `asn1:"optional,explicit,tag:0"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+// SubjectKeyIdentifier returns the subjectKeyIdentifier [0] choice of
+// RecipientIdentifier
+func SubjectKeyIdentifier(ski []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: ski}
+}
+
+// IssuerAndSerialNumber returns the issuerAndSerialNumber choice of
+// RecipientIdentifier, issuer being the DER encoded Name of the
+// certificate issuer (x509.Certificate.RawIssuer)
+func IssuerAndSerialNumber(issuer []byte, serial *big.Int) (asn1.RawValue, error) {
+	der, err := asn1.Marshal(issuerAndSerialNumber{asn1.RawValue{FullBytes: issuer}, serial})
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+	return asn1.RawValue{FullBytes: der}, nil
+}
+
+func kemAlgorithm(params *kyber.Parameters) (asn1.ObjectIdentifier, asn1.ObjectIdentifier, int, error) {
+	if params.KYBER_MODE != kyber.KYBER_MODE_MLKEM {
+		return nil, nil, 0, ErrUnsupportedAlgorithm
+	}
+	switch params.KYBER_K {
+	case 2:
+		return OIDMLKEM512, OIDAES128Wrap, 16, nil
+	case 3:
+		return OIDMLKEM768, OIDAES256Wrap, 32, nil
+	case 4:
+		return OIDMLKEM1024, OIDAES256Wrap, 32, nil
+	}
+	return nil, nil, 0, ErrUnsupportedAlgorithm
+}
+
+func deriveKEK(ss []byte, wrap pkix.AlgorithmIdentifier, kekLength int, ukm []byte) ([]byte, error) {
+	info, err := asn1.Marshal(kemOtherInfo{wrap, kekLength, ukm})
+	if err != nil {
+		return nil, err
+	}
+	kek := make([]byte, kekLength)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ss, nil, info), kek); err != nil {
+		return nil, err
+	}
+	return kek, nil
+}
+
+/*************************************************
+* Name:        NewKEMRecipientInfo
+*
+* Description: Encapsulates to pub, derives the key-encryption key
+*              and wraps the content-encryption key cek
+*
+* Arguments:   - pub *kyber.PublicKey: recipient ML-KEM public key
+*              - rid asn1.RawValue: recipient identifier
+*              - cek []byte: content-encryption key, a multiple of
+*                8 bytes and at least 16
+*              - ukm []byte: user keying material, may be nil
+*
+* Returns      - ri *KEMRecipientInfo: output recipient info
+*              - err error: ErrUnsupportedAlgorithm for round-3
+*                Kyber, non-nil on other failures
+**************************************************/
+func NewKEMRecipientInfo(pub *kyber.PublicKey, rid asn1.RawValue, cek, ukm []byte) (*KEMRecipientInfo, error) {
+	return NewKEMRecipientInfoRand(pub, rid, cek, ukm, rand.Reader)
+}
+
+// NewKEMRecipientInfoRand is NewKEMRecipientInfo drawing its randomness
+// from rand
+func NewKEMRecipientInfoRand(pub *kyber.PublicKey, rid asn1.RawValue, cek, ukm []byte, rand io.Reader) (*KEMRecipientInfo, error) {
+	kemOID, wrapOID, kekLength, err := kemAlgorithm(pub.Parameters())
+	if err != nil {
+		return nil, err
+	}
+	if len(ukm) == 0 {
+		ukm = nil
+	}
+	ct, ss, err := pub.EncapsulateRand(rand)
+	if err != nil {
+		return nil, err
+	}
+	ri := &KEMRecipientInfo{
+		RID:       rid,
+		KEM:       pkix.AlgorithmIdentifier{Algorithm: kemOID},
+		KEMCT:     ct,
+		KDF:       pkix.AlgorithmIdentifier{Algorithm: OIDHKDFSHA256},
+		KEKLength: kekLength,
+		UKM:       ukm,
+		Wrap:      pkix.AlgorithmIdentifier{Algorithm: wrapOID},
+	}
+	kek, err := deriveKEK(ss, ri.Wrap, ri.KEKLength, ri.UKM)
+	if err != nil {
+		return nil, err
+	}
+	if ri.EncryptedKey, err = keyWrap(kek, cek); err != nil {
+		return nil, err
+	}
+	return ri, nil
+}
+
+// Marshal returns the DER encoding of the RecipientInfo ori [4]
+// alternative holding ri
+func (ri *KEMRecipientInfo) Marshal() ([]byte, error) {
+	value, err := asn1.Marshal(*ri)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.MarshalWithParams(otherRecipientInfo{OIDOriKEM, asn1.RawValue{FullBytes: value}}, "tag:4")
+}
+
+/*************************************************
+* Name:        ParseRecipientInfo
+*
+* Description: Parses the DER encoding of a RecipientInfo ori [4]
+*              alternative holding a KEMRecipientInfo
+*
+* Arguments:   - der []byte: input RecipientInfo
+*
+* Returns      - ri *KEMRecipientInfo: output recipient info
+*              - err error: non-nil on bad input
+**************************************************/
+func ParseRecipientInfo(der []byte) (*KEMRecipientInfo, error) {
+	var ori otherRecipientInfo
+	if rest, err := asn1.UnmarshalWithParams(der, &ori, "tag:4"); err != nil || len(rest) != 0 {
+		return nil, ErrMalformed
+	}
+	if !ori.Type.Equal(OIDOriKEM) {
+		return nil, fmt.Errorf("cms: other recipient info of type %s is not a KEMRecipientInfo", ori.Type)
+	}
+	ri := new(KEMRecipientInfo)
+	if rest, err := asn1.Unmarshal(ori.Value.FullBytes, ri); err != nil || len(rest) != 0 {
+		return nil, ErrMalformed
+	}
+	if ri.Version != 0 || ri.KEKLength < 1 || ri.KEKLength > 65535 {
+		return nil, ErrMalformed
+	}
+	return ri, nil
+}
+
+// Parameters returns the parameter set of the KEM of ri
+func (ri *KEMRecipientInfo) Parameters() (*kyber.Parameters, error) {
+	// parameters MUST be absent
+	if len(ri.KEM.Parameters.FullBytes) != 0 {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	switch {
+	case ri.KEM.Algorithm.Equal(OIDMLKEM512):
+		return kyber.NewMLKEMParameters(2), nil
+	case ri.KEM.Algorithm.Equal(OIDMLKEM768):
+		return kyber.NewMLKEMParameters(3), nil
+	case ri.KEM.Algorithm.Equal(OIDMLKEM1024):
+		return kyber.NewMLKEMParameters(4), nil
+	}
+	return nil, ErrUnsupportedAlgorithm
+}
+
+/*************************************************
+* Name:        Decrypt
+*
+* Description: Decapsulates kemct with priv, derives the
+*              key-encryption key and unwraps the content-encryption
+*              key
+*
+* Arguments:   - priv *kyber.PrivateKey: recipient private key
+*
+* Returns      - cek []byte: output content-encryption key
+*              - err error: ErrUnsupportedAlgorithm if ri does not match
+*                priv or uses other algorithms, ErrUnwrap if ri was not
+*                made for priv or was modified
+**************************************************/
+func (ri *KEMRecipientInfo) Decrypt(priv *kyber.PrivateKey) ([]byte, error) {
+	params, err := ri.Parameters()
+	if err != nil {
+		return nil, err
+	}
+	if params.KYBER_ID != priv.Parameters().KYBER_ID {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	if !ri.KDF.Algorithm.Equal(OIDHKDFSHA256) || len(ri.KDF.Parameters.FullBytes) != 0 {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	switch {
+	case ri.Wrap.Algorithm.Equal(OIDAES128Wrap) && ri.KEKLength == 16:
+	case ri.Wrap.Algorithm.Equal(OIDAES256Wrap) && ri.KEKLength == 32:
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+	if len(ri.KEMCT) != params.KYBER_CIPHERTEXTBYTES {
+		return nil, ErrMalformed
+	}
+	ss, err := priv.Decapsulate(ri.KEMCT)
+	if err != nil {
+		return nil, err
+	}
+	kek, err := deriveKEK(ss, ri.Wrap, ri.KEKLength, ri.UKM)
+	if err != nil {
+		return nil, err
+	}
+	return keyUnwrap(kek, ri.EncryptedKey)
+}
@@ -0,0 +1,172 @@
+package cms
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+
+	kyber "github.com/depressi0n/kyber-go"
+)
+
+func fromHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestKeyWrap(t *testing.T) {
+	// RFC 3394, sections 4.1, 4.3 and 4.6
+	for _, v := range []struct{ kek, key, wrapped string }{
+		{"000102030405060708090A0B0C0D0E0F", "00112233445566778899AABBCCDDEEFF",
+			"1FA68B0A8112B447AEF34BD8FB5A7B829D3E862371D2CFE5"},
+		{"000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F", "00112233445566778899AABBCCDDEEFF",
+			"64E8C3F9CE0F5BA263E9777905818A2A93C8191E7D6E8AE7"},
+		{"000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F", "00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F",
+			"28C9F404C4B810F4CBCCB35CFB87F8263F5786E2D80ED326CBC7F0E71A99F43BFB988B9B7A02DD21"},
+	} {
+		kek, key, wrapped := fromHex(v.kek), fromHex(v.key), fromHex(v.wrapped)
+		got, err := keyWrap(kek, key)
+		if err != nil || !bytes.Equal(got, wrapped) {
+			t.Errorf("wrap: got %X, %v", got, err)
+		}
+		got, err = keyUnwrap(kek, wrapped)
+		if err != nil || !bytes.Equal(got, key) {
+			t.Errorf("unwrap: got %X, %v", got, err)
+		}
+		wrapped[0] ^= 1
+		if _, err := keyUnwrap(kek, wrapped); err != ErrUnwrap {
+			t.Errorf("tampered unwrap: got %v", err)
+		}
+	}
+}
+
+func TestKEMRecipientInfo(t *testing.T) {
+	rids := []asn1.RawValue{SubjectKeyIdentifier([]byte{1, 2, 3, 4})}
+	name, _ := asn1.Marshal(pkix.Name{CommonName: "recipient"}.ToRDNSequence())
+	rid, err := IssuerAndSerialNumber(name, big.NewInt(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rids = append(rids, rid)
+
+	cek := bytes.Repeat([]byte{0x5c}, 32)
+	for k := 2; k <= 4; k++ {
+		params := kyber.NewMLKEMParameters(k)
+		priv, err := kyber.GenerateKey(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, rid := range rids {
+			ukm := []byte(nil)
+			if i == 1 {
+				ukm = []byte("user keying material")
+			}
+			ri, err := NewKEMRecipientInfo(priv.PublicKey(), rid, cek, ukm)
+			if err != nil {
+				t.Fatal(err)
+			}
+			der, err := ri.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if der[0] != 0xa4 {
+				t.Errorf("%s: RecipientInfo tag %#x, want [4] constructed", params.KYBER_NAME, der[0])
+			}
+			parsed, err := ParseRecipientInfo(der)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(parsed.RID.FullBytes, mustMarshal(rid)) || !bytes.Equal(parsed.UKM, ukm) {
+				t.Errorf("%s: rid or ukm changed in round trip", params.KYBER_NAME)
+			}
+			wantKEK := 32
+			if k == 2 {
+				wantKEK = 16
+			}
+			if parsed.KEKLength != wantKEK {
+				t.Errorf("%s: kekLength %d, want %d", params.KYBER_NAME, parsed.KEKLength, wantKEK)
+			}
+			got, err := parsed.Decrypt(priv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, cek) {
+				t.Errorf("%s: decrypted CEK differs", params.KYBER_NAME)
+			}
+
+			other, _ := kyber.GenerateKey(params)
+			if _, err := parsed.Decrypt(other); !errors.Is(err, ErrUnwrap) {
+				t.Errorf("%s: wrong key: got %v", params.KYBER_NAME, err)
+			}
+		}
+	}
+}
+
+func mustMarshal(v asn1.RawValue) []byte {
+	b, err := asn1.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestKEMRecipientInfoErrors(t *testing.T) {
+	priv, _ := kyber.GenerateKey(kyber.NewMLKEMParameters(3))
+	rid := SubjectKeyIdentifier([]byte{1})
+	cek := make([]byte, 16)
+
+	round3, _ := kyber.GenerateKey(kyber.NewParameters(3))
+	if _, err := NewKEMRecipientInfo(round3.PublicKey(), rid, cek, nil); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("round-3 key: got %v", err)
+	}
+	if _, err := NewKEMRecipientInfo(priv.PublicKey(), rid, cek[:15], nil); err == nil {
+		t.Error("15-byte CEK accepted")
+	}
+
+	ri, _ := NewKEMRecipientInfo(priv.PublicKey(), rid, cek, []byte("ukm"))
+	for name, modify := range map[string]func(ri *KEMRecipientInfo){
+		"ukm":       func(ri *KEMRecipientInfo) { ri.UKM = []byte("UKM") },
+		"kemct":     func(ri *KEMRecipientInfo) { ri.KEMCT[0] ^= 1 },
+		"encrypted": func(ri *KEMRecipientInfo) { ri.EncryptedKey[0] ^= 1 },
+	} {
+		bad := *ri
+		bad.KEMCT = append([]byte{}, ri.KEMCT...)
+		bad.EncryptedKey = append([]byte{}, ri.EncryptedKey...)
+		modify(&bad)
+		if _, err := bad.Decrypt(priv); !errors.Is(err, ErrUnwrap) {
+			t.Errorf("modified %s: got %v", name, err)
+		}
+	}
+	for name, modify := range map[string]func(ri *KEMRecipientInfo){
+		"kem":       func(ri *KEMRecipientInfo) { ri.KEM.Algorithm = OIDMLKEM1024 },
+		"kdf":       func(ri *KEMRecipientInfo) { ri.KDF.Algorithm = OIDAES256Wrap },
+		"wrap":      func(ri *KEMRecipientInfo) { ri.Wrap.Algorithm = OIDAES128Wrap },
+		"kekLength": func(ri *KEMRecipientInfo) { ri.KEKLength = 24 },
+	} {
+		bad := *ri
+		modify(&bad)
+		if _, err := bad.Decrypt(priv); !errors.Is(err, ErrUnsupportedAlgorithm) {
+			t.Errorf("modified %s: got %v", name, err)
+		}
+	}
+
+	der, _ := ri.Marshal()
+	if _, err := ParseRecipientInfo(der[:len(der)-1]); err == nil {
+		t.Error("truncated RecipientInfo accepted")
+	}
+	if _, err := ParseRecipientInfo(append(der, 0)); err == nil {
+		t.Error("trailing data accepted")
+	}
+	bad := *ri
+	bad.Version = 2
+	der, _ = bad.Marshal()
+	if _, err := ParseRecipientInfo(der); err == nil {
+		t.Error("version 2 accepted")
+	}
+}
@@ -0,0 +1,270 @@
+// Package envelope encrypts one payload to many Kyber public keys, which
+// may belong to different parameter sets.
+//
+// A random 32-byte file key encrypts the payload once; each recipient
+// gets a stanza wrapping the file key under a KEM shared secret. Layout:
+//
+//	version (1) || count (2, big endian) || stanza * count || payload
+//	stanza  = KYBER_ID (1) || fingerprint (32) || KEM cipher text ||
+//	          wrapped file key (32 + 16)
+//	payload = ChaCha20-Poly1305(file key, nonce 0, aad = header)
+//
+// where the header is every byte before the payload, so the recipient
+// list cannot be changed. The wrapping key of a stanza is
+// HKDF-SHA256(ss, salt = KEM cipher text, info = label || fingerprint).
+package envelope
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	kyber "github.com/depressi0n/kyber-go"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	Version         = 1
+	FingerprintSize = sha256.Size
+
+	fileKeySize = chacha20poly1305.KeySize
+	wrappedSize = fileKeySize + chacha20poly1305.Overhead
+	wrapLabel   = "kyber-go envelope"
+)
+
+var (
+	ErrNoRecipients       = errors.New("envelope: no recipients")
+	ErrDuplicateRecipient = errors.New("envelope: duplicate recipient")
+	ErrNotRecipient       = errors.New("envelope: no stanza for this key")
+	ErrMalformed          = errors.New("envelope: malformed envelope")
+	ErrAuthentication     = errors.New("envelope: authentication failed")
+)
+
+// Fingerprint identifies a public key: SHA-256(KYBER_ID || pk)
+type Fingerprint [FingerprintSize]byte
+
+// FingerprintOf returns the fingerprint of pub
+func FingerprintOf(pub *kyber.PublicKey) Fingerprint {
+	h := sha256.New()
+	h.Write([]byte{pub.Parameters().KYBER_ID})
+	h.Write(pub.Bytes())
+	var fp Fingerprint
+	h.Sum(fp[:0])
+	return fp
+}
+
+// Stanza is the per-recipient part of an envelope
+type Stanza struct {
+	Params      *kyber.Parameters
+	Fingerprint Fingerprint
+	Ciphertext  []byte // KYBER_CIPHERTEXTBYTES
+	WrappedKey  []byte
+}
+
+var zeroNonce = make([]byte, chacha20poly1305.NonceSize)
+
+func wrapKey(ss, ct []byte, fp Fingerprint) ([]byte, error) {
+	key := make([]byte, chacha20poly1305.KeySize)
+	info := append([]byte(wrapLabel), fp[:]...)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ss, ct, info), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+/*************************************************
+* Name:        Encrypt
+*
+* Description: Encrypts plaintext once and wraps its key to every
+*              recipient
+*
+* Arguments:   - recipients []*kyber.PublicKey: recipient public keys
+*              - plaintext []byte: input payload
+*
+* Returns      - env []byte: output envelope
+*              - err error: ErrNoRecipients or ErrDuplicateRecipient on
+*                bad input, non-nil on other failures
+**************************************************/
+func Encrypt(recipients []*kyber.PublicKey, plaintext []byte) ([]byte, error) {
+	return EncryptRand(recipients, plaintext, rand.Reader)
+}
+
+// EncryptRand is Encrypt drawing its randomness from rand
+func EncryptRand(recipients []*kyber.PublicKey, plaintext []byte, rand io.Reader) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, ErrNoRecipients
+	}
+	if len(recipients) > 0xffff {
+		return nil, errors.New("envelope: too many recipients")
+	}
+	fileKey := make([]byte, fileKeySize)
+	if _, err := io.ReadFull(rand, fileKey); err != nil {
+		return nil, err
+	}
+
+	out := []byte{Version}
+	out = binary.BigEndian.AppendUint16(out, uint16(len(recipients)))
+	seen := make(map[Fingerprint]bool)
+	for _, pub := range recipients {
+		fp := FingerprintOf(pub)
+		if seen[fp] {
+			return nil, ErrDuplicateRecipient
+		}
+		seen[fp] = true
+
+		ct, ss, err := pub.EncapsulateRand(rand)
+		if err != nil {
+			return nil, err
+		}
+		key, err := wrapKey(ss, ct, fp)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := chacha20poly1305.New(key)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, pub.Parameters().KYBER_ID)
+		out = append(out, fp[:]...)
+		out = append(out, ct...)
+		out = aead.Seal(out, zeroNonce, fileKey, nil)
+	}
+
+	aead, err := chacha20poly1305.New(fileKey)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(out, zeroNonce, plaintext, out), nil
+}
+
+/*************************************************
+* Name:        Parse
+*
+* Description: Splits an envelope into its stanzas, header and
+*              encrypted payload
+*
+* Arguments:   - env []byte: input envelope
+*
+* Returns      - stanzas []Stanza: output stanzas, in order
+*              - header []byte: bytes authenticated by the payload
+*              - payload []byte: encrypted payload
+*              - err error: ErrMalformed on bad input
+**************************************************/
+func Parse(env []byte) ([]Stanza, []byte, []byte, error) {
+	if len(env) < 3 || env[0] != Version {
+		return nil, nil, nil, ErrMalformed
+	}
+	count := int(binary.BigEndian.Uint16(env[1:]))
+	if count == 0 {
+		return nil, nil, nil, ErrMalformed
+	}
+	b := env[3:]
+	stanzas := make([]Stanza, 0, count)
+	for i := 0; i < count; i++ {
+		if len(b) < 1+FingerprintSize {
+			return nil, nil, nil, ErrMalformed
+		}
+		params, err := kyber.ParametersByID(b[0])
+		if err != nil {
+			return nil, nil, nil, ErrMalformed
+		}
+		n := 1 + FingerprintSize + params.KYBER_CIPHERTEXTBYTES + wrappedSize
+		if len(b) < n {
+			return nil, nil, nil, ErrMalformed
+		}
+		s := Stanza{Params: params}
+		copy(s.Fingerprint[:], b[1:])
+		s.Ciphertext = b[1+FingerprintSize : n-wrappedSize]
+		s.WrappedKey = b[n-wrappedSize : n]
+		stanzas = append(stanzas, s)
+		b = b[n:]
+	}
+	if len(b) < chacha20poly1305.Overhead {
+		return nil, nil, nil, ErrMalformed
+	}
+	headerLen := len(env) - len(b)
+	return stanzas, env[:headerLen], b, nil
+}
+
+// Recipients returns the fingerprints of the recipients of env
+func Recipients(env []byte) ([]Fingerprint, error) {
+	stanzas, _, _, err := Parse(env)
+	if err != nil {
+		return nil, err
+	}
+	fps := make([]Fingerprint, len(stanzas))
+	for i, s := range stanzas {
+		fps[i] = s.Fingerprint
+	}
+	return fps, nil
+}
+
+/*************************************************
+* Name:        Decrypt
+*
+* Description: Finds the stanza of priv by fingerprint, unwraps the
+*              file key and decrypts the payload
+*
+* Arguments:   - priv *kyber.PrivateKey: recipient private key
+*              - env []byte: input envelope
+*
+* Returns      - plaintext []byte: output payload
+*              - err error: ErrNotRecipient if no stanza matches priv,
+*                ErrAuthentication if the envelope was modified
+**************************************************/
+func Decrypt(priv *kyber.PrivateKey, env []byte) ([]byte, error) {
+	stanzas, header, payload, err := Parse(env)
+	if err != nil {
+		return nil, err
+	}
+	fp := FingerprintOf(priv.PublicKey())
+	for _, s := range stanzas {
+		if s.Fingerprint != fp || s.Params.KYBER_ID != priv.Parameters().KYBER_ID {
+			continue
+		}
+		ss, err := priv.Decapsulate(s.Ciphertext)
+		if err != nil {
+			return nil, err
+		}
+		key, err := wrapKey(ss, s.Ciphertext, fp)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := chacha20poly1305.New(key)
+		if err != nil {
+			return nil, err
+		}
+		fileKey, err := aead.Open(nil, zeroNonce, s.WrappedKey, nil)
+		if err != nil {
+			return nil, ErrAuthentication
+		}
+		aead, err = chacha20poly1305.New(fileKey)
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err := aead.Open(nil, zeroNonce, payload, header)
+		if err != nil {
+			return nil, ErrAuthentication
+		}
+		return plaintext, nil
+	}
+	return nil, ErrNotRecipient
+}
+
+// Contains reports whether env has a stanza for pub
+func Contains(env []byte, pub *kyber.PublicKey) bool {
+	fps, err := Recipients(env)
+	if err != nil {
+		return false
+	}
+	fp := FingerprintOf(pub)
+	for _, f := range fps {
+		if f == fp {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package envelope
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	kyber "github.com/depressi0n/kyber-go"
+)
+
+func TestEnvelope(t *testing.T) {
+	var privs []*kyber.PrivateKey
+	var pubs []*kyber.PublicKey
+	for _, params := range []*kyber.Parameters{kyber.NewParameters(2), kyber.NewMLKEMParameters(3), kyber.NewMLKEMParameters(4)} {
+		priv, err := kyber.GenerateKey(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		privs = append(privs, priv)
+		pubs = append(pubs, priv.PublicKey())
+	}
+
+	msg := []byte("release artifact")
+	env, err := Encrypt(pubs, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fps, err := Recipients(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, priv := range privs {
+		if fps[i] != FingerprintOf(pubs[i]) || !Contains(env, pubs[i]) {
+			t.Errorf("recipient %d not listed", i)
+		}
+		got, err := Decrypt(priv, env)
+		if err != nil {
+			t.Fatalf("recipient %d: %v", i, err)
+		}
+		if !bytes.Equal(got, msg) {
+			t.Errorf("recipient %d: payload differs", i)
+		}
+	}
+
+	outsider, _ := kyber.GenerateKey(kyber.NewMLKEMParameters(3))
+	if _, err := Decrypt(outsider, env); !errors.Is(err, ErrNotRecipient) {
+		t.Errorf("outsider: got %v", err)
+	}
+	if Contains(env, outsider.PublicKey()) {
+		t.Error("outsider listed as recipient")
+	}
+
+	if _, err := Encrypt(nil, msg); !errors.Is(err, ErrNoRecipients) {
+		t.Errorf("no recipients: got %v", err)
+	}
+	if _, err := Encrypt([]*kyber.PublicKey{pubs[0], pubs[0]}, msg); !errors.Is(err, ErrDuplicateRecipient) {
+		t.Errorf("duplicate recipient: got %v", err)
+	}
+}
+
+func TestEnvelopeTampering(t *testing.T) {
+	a, _ := kyber.GenerateKey(kyber.NewMLKEMParameters(3))
+	b, _ := kyber.GenerateKey(kyber.NewMLKEMParameters(3))
+	env, err := Encrypt([]*kyber.PublicKey{a.PublicKey(), b.PublicKey()}, []byte("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stanzas, header, _, err := Parse(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// dropping b's stanza changes the header the payload authenticates
+	n := 1 + FingerprintSize + len(stanzas[1].Ciphertext) + len(stanzas[1].WrappedKey)
+	dropped := append([]byte{}, env[:len(header)-n]...)
+	dropped[2] = 1
+	dropped = append(dropped, env[len(header):]...)
+	if _, err := Decrypt(a, dropped); !errors.Is(err, ErrAuthentication) {
+		t.Errorf("dropped stanza: got %v", err)
+	}
+
+	// a modified wrapped key or payload
+	for _, i := range []int{len(header) - 1, len(env) - 1} {
+		bad := append([]byte{}, env...)
+		bad[i] ^= 1
+		if _, err := Decrypt(b, bad); !errors.Is(err, ErrAuthentication) {
+			t.Errorf("tampered byte %d: got %v", i, err)
+		}
+	}
+
+	for _, bad := range [][]byte{nil, env[:3], env[:len(header)], {Version + 1, 0, 1}} {
+		if _, err := Decrypt(a, bad); !errors.Is(err, ErrMalformed) {
+			t.Errorf("malformed %x...: got %v", bad[:min(len(bad), 4)], err)
+		}
+	}
+}
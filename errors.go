@@ -0,0 +1,29 @@
+package kyber
+
+import "errors"
+
+var (
+	ErrInvalidPublicKeyLength  = errors.New("kyber: invalid public key length")
+	ErrInvalidSecretKeyLength  = errors.New("kyber: invalid secret key length")
+	ErrInvalidCiphertextLength = errors.New("kyber: invalid ciphertext length")
+	ErrInvalidMessageLength    = errors.New("kyber: invalid message length")
+	ErrInvalidCoinsLength      = errors.New("kyber: invalid coins length")
+	ErrInvalidSeedLength       = errors.New("kyber: invalid seed length")
+
+	ErrInvalidKexMessageLength = errors.New("kyber: invalid key exchange message length")
+	ErrKexState                = errors.New("kyber: key exchange step out of order or session already used")
+	ErrKexConfirmation         = errors.New("kyber: key confirmation failed")
+
+	ErrInvalidPublicKeyEncoding = errors.New("kyber: public key coefficient not reduced modulo q")
+	ErrInvalidSecretKeyHash     = errors.New("kyber: secret key hash does not match embedded public key")
+
+	ErrUnsupportedParameters = errors.New("kyber: operation not supported for this parameter set")
+	ErrCiphertextParameters  = errors.New("kyber: ciphertext is for another parameter set")
+
+	ErrSeedUnavailable = errors.New("kyber: private key seed not available")
+	ErrSeedMismatch    = errors.New("kyber: seed does not match expanded private key")
+
+	ErrInvalidSealedMessage = errors.New("kyber: malformed sealed message")
+	ErrParametersMismatch   = errors.New("kyber: sealed message is for another parameter set")
+	ErrDecryption           = errors.New("kyber: message authentication failed")
+)
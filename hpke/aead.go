@@ -0,0 +1,91 @@
+package hpke
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// AEAD is one of the authenticated encryption schemes of RFC 9180
+type AEAD interface {
+	ID() uint16
+	keySize() int   // Nk
+	nonceSize() int // Nn
+	new(key []byte) (cipher.AEAD, error)
+}
+
+type aesGCM struct {
+	id uint16
+	nk int
+}
+
+type chachaPoly struct{}
+
+type exportOnly struct{}
+
+var (
+	aes128GCM = &aesGCM{0x0001, 16}
+	aes256GCM = &aesGCM{0x0002, 32}
+)
+
+// AES128GCM returns the AES-128-GCM AEAD (0x0001)
+func AES128GCM() AEAD { return aes128GCM }
+
+// AES256GCM returns the AES-256-GCM AEAD (0x0002)
+func AES256GCM() AEAD { return aes256GCM }
+
+// ChaCha20Poly1305 returns the ChaCha20Poly1305 AEAD (0x0003)
+func ChaCha20Poly1305() AEAD { return chachaPoly{} }
+
+// ExportOnly returns the export-only AEAD (0xFFFF), for contexts
+// that are only used to derive secrets with Export
+func ExportOnly() AEAD { return exportOnly{} }
+
+// NewAEAD returns the AEAD with the given RFC 9180 identifier
+func NewAEAD(id uint16) (AEAD, error) {
+	switch id {
+	case 0x0001:
+		return aes128GCM, nil
+	case 0x0002:
+		return aes256GCM, nil
+	case 0x0003:
+		return chachaPoly{}, nil
+	case 0xFFFF:
+		return exportOnly{}, nil
+	}
+	return nil, fmt.Errorf("hpke: unsupported AEAD %#04x", id)
+}
+
+func (a *aesGCM) ID() uint16 { return a.id }
+
+func (a *aesGCM) keySize() int { return a.nk }
+
+func (a *aesGCM) nonceSize() int { return 12 }
+
+func (a *aesGCM) new(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (chachaPoly) ID() uint16 { return 0x0003 }
+
+func (chachaPoly) keySize() int { return chacha20poly1305.KeySize }
+
+func (chachaPoly) nonceSize() int { return chacha20poly1305.NonceSize }
+
+func (chachaPoly) new(key []byte) (cipher.AEAD, error) {
+	return chacha20poly1305.New(key)
+}
+
+func (exportOnly) ID() uint16 { return 0xFFFF }
+
+func (exportOnly) keySize() int { return 0 }
+
+func (exportOnly) nonceSize() int { return 0 }
+
+func (exportOnly) new([]byte) (cipher.AEAD, error) { return nil, nil }
@@ -0,0 +1,284 @@
+// Package hpke implements the base and PSK modes of Hybrid Public Key
+// Encryption (RFC 9180) with the ML-KEM and X-Wing KEMs of
+// draft-ietf-hpke-pq.
+package hpke
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const versionLabel = "HPKE-v1"
+
+const (
+	modeBase byte = 0x00
+	modePSK  byte = 0x01
+)
+
+// Suite is a combination of KEM, KDF and AEAD
+type Suite struct {
+	kem  KEM
+	kdf  KDF
+	aead AEAD
+	id   []byte
+}
+
+type context struct {
+	suite          *Suite
+	aead           cipher.AEAD
+	baseNonce      []byte
+	seq            uint64
+	exporterSecret []byte
+}
+
+// Sender is the encryption context of the sender
+type Sender struct {
+	context
+}
+
+// Receiver is the decryption context of the recipient
+type Receiver struct {
+	context
+}
+
+// NewSuite returns the ciphersuite of the given algorithms
+func NewSuite(kem KEM, kdf KDF, aead AEAD) *Suite {
+	id := []byte("HPKE")
+	id = binary.BigEndian.AppendUint16(id, kem.ID())
+	id = binary.BigEndian.AppendUint16(id, kdf.ID())
+	id = binary.BigEndian.AppendUint16(id, aead.ID())
+	return &Suite{kem: kem, kdf: kdf, aead: aead, id: id}
+}
+
+// KEM returns the KEM of the suite
+func (s *Suite) KEM() KEM { return s.kem }
+
+/*************************************************
+* Name:        (*Suite).SetupBaseS
+*
+* Description: Encapsulates to pkR and sets up a base mode
+*              sender context
+*
+* Arguments:   - pkR PublicKey: recipient public key
+*              - info []byte: application supplied information
+*              - rand io.Reader: source of randomness, or nil
+*                for crypto/rand
+*
+* Returns      - enc []byte: output encapsulated key
+*              - s *Sender: output sender context
+*              - err error: non-nil on failure
+**************************************************/
+func (s *Suite) SetupBaseS(pkR PublicKey, info []byte, rand io.Reader) ([]byte, *Sender, error) {
+	return s.setupS(modeBase, pkR, info, nil, nil, rand)
+}
+
+/*************************************************
+* Name:        (*Suite).SetupBaseR
+*
+* Description: Decapsulates enc and sets up a base mode
+*              recipient context
+*
+* Arguments:   - enc []byte: input encapsulated key
+*              - skR PrivateKey: recipient private key
+*              - info []byte: application supplied information
+*
+* Returns      - r *Receiver: output recipient context
+*              - err error: non-nil on failure
+**************************************************/
+func (s *Suite) SetupBaseR(enc []byte, skR PrivateKey, info []byte) (*Receiver, error) {
+	return s.setupR(modeBase, enc, skR, info, nil, nil)
+}
+
+// SetupPSKS is SetupBaseS in PSK mode, additionally authenticating
+// the sender as a holder of psk, identified by pskID
+func (s *Suite) SetupPSKS(pkR PublicKey, info, psk, pskID []byte, rand io.Reader) ([]byte, *Sender, error) {
+	return s.setupS(modePSK, pkR, info, psk, pskID, rand)
+}
+
+// SetupPSKR is SetupBaseR in PSK mode
+func (s *Suite) SetupPSKR(enc []byte, skR PrivateKey, info, psk, pskID []byte) (*Receiver, error) {
+	return s.setupR(modePSK, enc, skR, info, psk, pskID)
+}
+
+func (s *Suite) setupS(mode byte, pkR PublicKey, info, psk, pskID []byte, r io.Reader) ([]byte, *Sender, error) {
+	if pkR.KEM().ID() != s.kem.ID() {
+		return nil, nil, errors.New("hpke: public key does not belong to the suite KEM")
+	}
+	if r == nil {
+		r = rand.Reader
+	}
+	ss, enc, err := pkR.Encap(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, err := s.keySchedule(mode, ss, info, psk, pskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return enc, &Sender{*ctx}, nil
+}
+
+func (s *Suite) setupR(mode byte, enc []byte, skR PrivateKey, info, psk, pskID []byte) (*Receiver, error) {
+	if skR.KEM().ID() != s.kem.ID() {
+		return nil, errors.New("hpke: private key does not belong to the suite KEM")
+	}
+	ss, err := skR.Decap(enc)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err := s.keySchedule(mode, ss, info, psk, pskID)
+	if err != nil {
+		return nil, err
+	}
+	return &Receiver{*ctx}, nil
+}
+
+func verifyPSKInputs(mode byte, psk, pskID []byte) error {
+	gotPSK := len(psk) != 0
+	gotPSKID := len(pskID) != 0
+	if gotPSK != gotPSKID {
+		return errors.New("hpke: inconsistent PSK inputs")
+	}
+	if gotPSK && mode == modeBase {
+		return errors.New("hpke: PSK input provided when not needed")
+	}
+	if !gotPSK && mode == modePSK {
+		return errors.New("hpke: missing required PSK input")
+	}
+	if gotPSK && len(psk) < 32 {
+		return errors.New("hpke: PSK must have at least 32 bytes of entropy")
+	}
+	return nil
+}
+
+func (s *Suite) keySchedule(mode byte, sharedSecret, info, psk, pskID []byte) (*context, error) {
+	if err := verifyPSKInputs(mode, psk, pskID); err != nil {
+		return nil, err
+	}
+
+	pskIDHash := s.kdf.labeledExtract(s.id, nil, "psk_id_hash", pskID)
+	infoHash := s.kdf.labeledExtract(s.id, nil, "info_hash", info)
+	ksContext := append([]byte{mode}, pskIDHash...)
+	ksContext = append(ksContext, infoHash...)
+
+	secret := s.kdf.labeledExtract(s.id, sharedSecret, "secret", psk)
+
+	exporterSecret, err := s.kdf.labeledExpand(s.id, secret, "exp", ksContext, uint16(s.kdf.size()))
+	if err != nil {
+		return nil, err
+	}
+	ctx := &context{suite: s, exporterSecret: exporterSecret}
+	if _, ok := s.aead.(exportOnly); ok {
+		return ctx, nil
+	}
+
+	key, err := s.kdf.labeledExpand(s.id, secret, "key", ksContext, uint16(s.aead.keySize()))
+	if err != nil {
+		return nil, err
+	}
+	ctx.baseNonce, err = s.kdf.labeledExpand(s.id, secret, "base_nonce", ksContext, uint16(s.aead.nonceSize()))
+	if err != nil {
+		return nil, err
+	}
+	ctx.aead, err = s.aead.new(key)
+	if err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
+
+func (ctx *context) nextNonce() ([]byte, error) {
+	if ctx.aead == nil {
+		return nil, errors.New("hpke: export-only context cannot seal or open")
+	}
+	// the sequence number is at most 64 bits while Nn is at least 8 bytes,
+	// so the only overflow to guard against is the counter wrapping
+	if ctx.seq == ^uint64(0) {
+		return nil, errors.New("hpke: message limit reached")
+	}
+	nonce := make([]byte, len(ctx.baseNonce))
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], ctx.seq)
+	for i := range nonce {
+		nonce[i] ^= ctx.baseNonce[i]
+	}
+	return nonce, nil
+}
+
+/*************************************************
+* Name:        Export
+*
+* Description: Derives a secret of length L bound to exporterContext
+*              from the exporter secret of the context
+*
+* Arguments:   - exporterContext []byte: application context
+*              - L uint16: length of the output
+*
+* Returns      - secret []byte: output exported secret
+*              - err error: non-nil if L is too large
+**************************************************/
+func (ctx *context) Export(exporterContext []byte, L uint16) ([]byte, error) {
+	return ctx.suite.kdf.labeledExpand(ctx.suite.id, ctx.exporterSecret, "sec", exporterContext, L)
+}
+
+// Seal encrypts and authenticates plaintext and aad with the next nonce
+func (s *Sender) Seal(aad, plaintext []byte) ([]byte, error) {
+	nonce, err := s.nextNonce()
+	if err != nil {
+		return nil, err
+	}
+	ct := s.aead.Seal(nil, nonce, plaintext, aad)
+	s.seq++
+	return ct, nil
+}
+
+// Open authenticates and decrypts ciphertext and aad with the next nonce
+func (r *Receiver) Open(aad, ciphertext []byte) ([]byte, error) {
+	nonce, err := r.nextNonce()
+	if err != nil {
+		return nil, err
+	}
+	pt, err := r.aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, err
+	}
+	r.seq++
+	return pt, nil
+}
+
+/*************************************************
+* Name:        (*Suite).Seal
+*
+* Description: Single-shot base mode encryption of plaintext to pkR
+*
+* Arguments:   - pkR PublicKey: recipient public key
+*              - info []byte: application supplied information
+*              - aad []byte: additional authenticated data
+*              - plaintext []byte: input message
+*
+* Returns      - enc []byte: output encapsulated key
+*              - ct []byte: output ciphertext
+*              - err error: non-nil on failure
+**************************************************/
+func (s *Suite) Seal(pkR PublicKey, info, aad, plaintext []byte) ([]byte, []byte, error) {
+	enc, sender, err := s.SetupBaseS(pkR, info, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	ct, err := sender.Seal(aad, plaintext)
+	if err != nil {
+		return nil, nil, err
+	}
+	return enc, ct, nil
+}
+
+// Open is the single-shot base mode decryption matching Seal
+func (s *Suite) Open(enc []byte, skR PrivateKey, info, aad, ciphertext []byte) ([]byte, error) {
+	receiver, err := s.SetupBaseR(enc, skR, info)
+	if err != nil {
+		return nil, err
+	}
+	return receiver.Open(aad, ciphertext)
+}
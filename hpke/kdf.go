@@ -0,0 +1,82 @@
+package hpke
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// KDF is one of the two-stage key derivation functions of RFC 9180
+type KDF interface {
+	ID() uint16
+	size() int // Nh
+	labeledExtract(suiteID, salt []byte, label string, ikm []byte) []byte
+	labeledExpand(suiteID, prk []byte, label string, info []byte, length uint16) ([]byte, error)
+}
+
+type hkdfKDF struct {
+	id   uint16
+	hash func() hash.Hash
+}
+
+var (
+	hkdfSHA256 = &hkdfKDF{0x0001, sha256.New}
+	hkdfSHA384 = &hkdfKDF{0x0002, sha512.New384}
+	hkdfSHA512 = &hkdfKDF{0x0003, sha512.New}
+)
+
+// HKDFSHA256 returns the HKDF-SHA256 KDF (0x0001)
+func HKDFSHA256() KDF { return hkdfSHA256 }
+
+// HKDFSHA384 returns the HKDF-SHA384 KDF (0x0002)
+func HKDFSHA384() KDF { return hkdfSHA384 }
+
+// HKDFSHA512 returns the HKDF-SHA512 KDF (0x0003)
+func HKDFSHA512() KDF { return hkdfSHA512 }
+
+// NewKDF returns the KDF with the given RFC 9180 identifier
+func NewKDF(id uint16) (KDF, error) {
+	switch id {
+	case 0x0001:
+		return hkdfSHA256, nil
+	case 0x0002:
+		return hkdfSHA384, nil
+	case 0x0003:
+		return hkdfSHA512, nil
+	}
+	return nil, fmt.Errorf("hpke: unsupported KDF %#04x", id)
+}
+
+func (kdf *hkdfKDF) ID() uint16 { return kdf.id }
+
+func (kdf *hkdfKDF) size() int { return kdf.hash().Size() }
+
+func (kdf *hkdfKDF) labeledExtract(suiteID, salt []byte, label string, ikm []byte) []byte {
+	labeledIKM := make([]byte, 0, len(versionLabel)+len(suiteID)+len(label)+len(ikm))
+	labeledIKM = append(labeledIKM, versionLabel...)
+	labeledIKM = append(labeledIKM, suiteID...)
+	labeledIKM = append(labeledIKM, label...)
+	labeledIKM = append(labeledIKM, ikm...)
+	return hkdf.Extract(kdf.hash, labeledIKM, salt)
+}
+
+func (kdf *hkdfKDF) labeledExpand(suiteID, prk []byte, label string, info []byte, length uint16) ([]byte, error) {
+	if int(length) > 255*kdf.size() {
+		return nil, fmt.Errorf("hpke: expand length %d too large", length)
+	}
+	labeledInfo := binary.BigEndian.AppendUint16(nil, length)
+	labeledInfo = append(labeledInfo, versionLabel...)
+	labeledInfo = append(labeledInfo, suiteID...)
+	labeledInfo = append(labeledInfo, label...)
+	labeledInfo = append(labeledInfo, info...)
+	out := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.Expand(kdf.hash, prk, labeledInfo), out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
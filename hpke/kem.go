@@ -0,0 +1,182 @@
+package hpke
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	kyber "github.com/depressi0n/kyber-go"
+	"golang.org/x/crypto/sha3"
+)
+
+// KEM is a key encapsulation mechanism of RFC 9180
+//
+// A nil rand reader means crypto/rand throughout this package
+type KEM interface {
+	ID() uint16
+	GenerateKeyPair(rand io.Reader) (PrivateKey, error)
+	DeriveKeyPair(ikm []byte) (PrivateKey, error)
+	DeserializePublicKey(pkm []byte) (PublicKey, error)
+	DeserializePrivateKey(skm []byte) (PrivateKey, error)
+}
+
+// PublicKey is a KEM public key; Bytes is SerializePublicKey
+type PublicKey interface {
+	KEM() KEM
+	Bytes() []byte
+	Encap(rand io.Reader) (sharedSecret []byte, enc []byte, err error)
+}
+
+// PrivateKey is a KEM private key; Bytes is SerializePrivateKey
+type PrivateKey interface {
+	KEM() KEM
+	Bytes() ([]byte, error)
+	PublicKey() PublicKey
+	Decap(enc []byte) (sharedSecret []byte, err error)
+}
+
+// ML-KEM as HPKE KEM following draft-ietf-hpke-pq: the shared
+// secret is the ML-KEM shared secret and private keys are
+// serialized as the 64-byte seed d||z
+type mlkemKEM struct {
+	id     uint16
+	params *kyber.Parameters
+}
+
+var (
+	mlkem512  = &mlkemKEM{0x0040, kyber.NewMLKEMParameters(2)}
+	mlkem768  = &mlkemKEM{0x0041, kyber.NewMLKEMParameters(3)}
+	mlkem1024 = &mlkemKEM{0x0042, kyber.NewMLKEMParameters(4)}
+)
+
+// MLKEM512 returns the ML-KEM-512 KEM (0x0040)
+func MLKEM512() KEM { return mlkem512 }
+
+// MLKEM768 returns the ML-KEM-768 KEM (0x0041)
+func MLKEM768() KEM { return mlkem768 }
+
+// MLKEM1024 returns the ML-KEM-1024 KEM (0x0042)
+func MLKEM1024() KEM { return mlkem1024 }
+
+func mlkemFor(params *kyber.Parameters) (*mlkemKEM, error) {
+	if params.KYBER_MODE != kyber.KYBER_MODE_MLKEM {
+		return nil, errors.New("hpke: only ML-KEM parameter sets have HPKE KEM identifiers")
+	}
+	switch params.KYBER_K {
+	case 2:
+		return mlkem512, nil
+	case 3:
+		return mlkem768, nil
+	case 4:
+		return mlkem1024, nil
+	}
+	return nil, errors.New("hpke: unsupported parameter set " + params.KYBER_NAME)
+}
+
+type mlkemPublicKey struct {
+	kem *mlkemKEM
+	pub *kyber.PublicKey
+}
+
+type mlkemPrivateKey struct {
+	kem  *mlkemKEM
+	priv *kyber.PrivateKey
+}
+
+// NewMLKEMPublicKey wraps an ML-KEM public key for use with HPKE
+func NewMLKEMPublicKey(pub *kyber.PublicKey) (PublicKey, error) {
+	kem, err := mlkemFor(pub.Parameters())
+	if err != nil {
+		return nil, err
+	}
+	return &mlkemPublicKey{kem, pub}, nil
+}
+
+// NewMLKEMPrivateKey wraps an ML-KEM private key for use with HPKE
+func NewMLKEMPrivateKey(priv *kyber.PrivateKey) (PrivateKey, error) {
+	kem, err := mlkemFor(priv.Parameters())
+	if err != nil {
+		return nil, err
+	}
+	return &mlkemPrivateKey{kem, priv}, nil
+}
+
+func (kem *mlkemKEM) ID() uint16 { return kem.id }
+
+func (kem *mlkemKEM) GenerateKeyPair(r io.Reader) (PrivateKey, error) {
+	if r == nil {
+		r = rand.Reader
+	}
+	priv, err := kyber.GenerateKeyRand(kem.params, r)
+	if err != nil {
+		return nil, err
+	}
+	return &mlkemPrivateKey{kem, priv}, nil
+}
+
+// DeriveKeyPair expands ikm to a seed with the SHAKE256 LabeledDerive
+// of draft-ietf-hpke-pq and generates the key pair from that seed
+func (kem *mlkemKEM) DeriveKeyPair(ikm []byte) (PrivateKey, error) {
+	seed := labeledDerive(kemSuiteID(kem.id), ikm, "DeriveKeyPair", nil, kyber.KYBER_SEEDBYTES)
+	return kem.DeserializePrivateKey(seed)
+}
+
+func (kem *mlkemKEM) DeserializePublicKey(pkm []byte) (PublicKey, error) {
+	pub, err := kyber.ParsePublicKey(kem.params, pkm)
+	if err != nil {
+		return nil, err
+	}
+	return &mlkemPublicKey{kem, pub}, nil
+}
+
+func (kem *mlkemKEM) DeserializePrivateKey(skm []byte) (PrivateKey, error) {
+	priv, err := kyber.NewPrivateKeyFromSeed(kem.params, skm)
+	if err != nil {
+		return nil, err
+	}
+	return &mlkemPrivateKey{kem, priv}, nil
+}
+
+func (pk *mlkemPublicKey) KEM() KEM { return pk.kem }
+
+func (pk *mlkemPublicKey) Bytes() []byte { return pk.pub.Bytes() }
+
+func (pk *mlkemPublicKey) Encap(rand io.Reader) ([]byte, []byte, error) {
+	ct, ss, err := pk.pub.EncapsulateRand(rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ss, ct, nil
+}
+
+func (sk *mlkemPrivateKey) KEM() KEM { return sk.kem }
+
+func (sk *mlkemPrivateKey) Bytes() ([]byte, error) { return sk.priv.Seed() }
+
+func (sk *mlkemPrivateKey) PublicKey() PublicKey {
+	return &mlkemPublicKey{sk.kem, sk.priv.PublicKey()}
+}
+
+func (sk *mlkemPrivateKey) Decap(enc []byte) ([]byte, error) {
+	return sk.priv.Decapsulate(enc)
+}
+
+func kemSuiteID(id uint16) []byte {
+	return binary.BigEndian.AppendUint16([]byte("KEM"), id)
+}
+
+// One-stage SHAKE256 LabeledDerive of draft-ietf-hpke-pq
+func labeledDerive(suiteID, ikm []byte, label string, context []byte, length uint16) []byte {
+	h := sha3.NewShake256()
+	h.Write(ikm)
+	h.Write([]byte(versionLabel))
+	h.Write(suiteID)
+	h.Write(binary.BigEndian.AppendUint16(nil, uint16(len(label))))
+	h.Write([]byte(label))
+	h.Write(binary.BigEndian.AppendUint16(nil, length))
+	h.Write(context)
+	out := make([]byte, length)
+	h.Read(out)
+	return out
+}
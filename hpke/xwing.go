@@ -0,0 +1,91 @@
+package hpke
+
+import (
+	"crypto/rand"
+	"io"
+
+	"github.com/depressi0n/kyber-go/xwing"
+)
+
+// X-Wing (MLKEM768-X25519) as HPKE KEM following draft-ietf-hpke-pq:
+// private keys are serialized as the 32-byte X-Wing seed
+type xwingKEM struct{}
+
+type xwingPublicKey struct {
+	pub *xwing.PublicKey
+}
+
+type xwingPrivateKey struct {
+	priv *xwing.PrivateKey
+}
+
+// XWing returns the MLKEM768-X25519 KEM (0x647a)
+func XWing() KEM { return xwingKEM{} }
+
+// NewXWingPublicKey wraps an X-Wing public key for use with HPKE
+func NewXWingPublicKey(pub *xwing.PublicKey) PublicKey {
+	return &xwingPublicKey{pub}
+}
+
+// NewXWingPrivateKey wraps an X-Wing private key for use with HPKE
+func NewXWingPrivateKey(priv *xwing.PrivateKey) PrivateKey {
+	return &xwingPrivateKey{priv}
+}
+
+func (xwingKEM) ID() uint16 { return 0x647a }
+
+func (xwingKEM) GenerateKeyPair(r io.Reader) (PrivateKey, error) {
+	if r == nil {
+		r = rand.Reader
+	}
+	priv, err := xwing.GenerateKey(r)
+	if err != nil {
+		return nil, err
+	}
+	return &xwingPrivateKey{priv}, nil
+}
+
+func (kem xwingKEM) DeriveKeyPair(ikm []byte) (PrivateKey, error) {
+	seed := labeledDerive(kemSuiteID(kem.ID()), ikm, "DeriveKeyPair", nil, xwing.SeedSize)
+	return kem.DeserializePrivateKey(seed)
+}
+
+func (xwingKEM) DeserializePublicKey(pkm []byte) (PublicKey, error) {
+	pub, err := xwing.ParsePublicKey(pkm)
+	if err != nil {
+		return nil, err
+	}
+	return &xwingPublicKey{pub}, nil
+}
+
+func (xwingKEM) DeserializePrivateKey(skm []byte) (PrivateKey, error) {
+	priv, err := xwing.NewPrivateKeyFromSeed(skm)
+	if err != nil {
+		return nil, err
+	}
+	return &xwingPrivateKey{priv}, nil
+}
+
+func (pk *xwingPublicKey) KEM() KEM { return xwingKEM{} }
+
+func (pk *xwingPublicKey) Bytes() []byte { return pk.pub.Bytes() }
+
+func (pk *xwingPublicKey) Encap(rand io.Reader) ([]byte, []byte, error) {
+	ct, ss, err := pk.pub.Encapsulate(rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ss, ct, nil
+}
+
+func (sk *xwingPrivateKey) KEM() KEM { return xwingKEM{} }
+
+func (sk *xwingPrivateKey) Bytes() ([]byte, error) { return sk.priv.Bytes(), nil }
+
+func (sk *xwingPrivateKey) PublicKey() PublicKey {
+	return &xwingPublicKey{sk.priv.PublicKey()}
+}
+
+func (sk *xwingPrivateKey) Decap(enc []byte) ([]byte, error) {
+	return sk.priv.Decapsulate(enc)
+}
@@ -0,0 +1,181 @@
+package hybrid
+
+import (
+	"crypto/sha256"
+	"io"
+
+	kyber "github.com/depressi0n/kyber-go"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/sha3"
+)
+
+// KDF selects how a combiner derives the shared secret
+type KDF int
+
+const (
+	// KDFConcatHKDF is HKDF-SHA256 with ikm ss1||ss2 and
+	// info label||ct1||ct2||pk1||pk2. The public keys are bound
+	// because a component such as ECDH gives a shared secret that
+	// does not depend on the recipient key
+	KDFConcatHKDF KDF = iota
+	// KDFKEMCombiner is the KEM combiner of the IETF hybrid KEM
+	// drafts: SHA3-256(ss1||ss2||ct1||ct2||pk1||pk2||label)
+	KDFKEMCombiner
+)
+
+const combinedSharedSecretSize = 32
+
+type combiner struct {
+	first, second KEM
+	kdf           KDF
+	label         []byte
+}
+
+/*************************************************
+* Name:        NewCombiner
+*
+* Description: Composes two KEMs into one. Public keys,
+*              private keys and ciphertexts are the
+*              concatenations of those of first and second
+*
+* Arguments:   - first KEM: first component
+*              - second KEM: second component
+*              - kdf KDF: shared secret derivation
+*              - label string: domain separation label,
+*                distinct for every combination in use
+*
+* Returns the combined KEM, with 32-byte shared secrets, or
+* ErrUnsupportedKDF if kdf is none of the KDF constants
+**************************************************/
+func NewCombiner(first, second KEM, kdf KDF, label string) (KEM, error) {
+	if kdf != KDFConcatHKDF && kdf != KDFKEMCombiner {
+		return nil, ErrUnsupportedKDF
+	}
+	return newCombiner(first, second, kdf, label), nil
+}
+
+func newCombiner(first, second KEM, kdf KDF, label string) *combiner {
+	return &combiner{first: first, second: second, kdf: kdf, label: []byte(label)}
+}
+
+// P256Kyber768 returns ML-KEM-768 combined with P-256
+func P256Kyber768() KEM {
+	return newCombiner(Kyber(kyber.NewMLKEMParameters(3)), ecdhP256, KDFKEMCombiner, "MLKEM768-P256")
+}
+
+// X448Kyber1024 returns ML-KEM-1024 combined with X448
+func X448Kyber1024() KEM {
+	return newCombiner(Kyber(kyber.NewMLKEMParameters(4)), X448(), KDFKEMCombiner, "MLKEM1024-X448")
+}
+
+func (c *combiner) Name() string { return string(c.label) }
+
+func (c *combiner) PublicKeySize() int {
+	return c.first.PublicKeySize() + c.second.PublicKeySize()
+}
+
+func (c *combiner) PrivateKeySize() int {
+	return c.first.PrivateKeySize() + c.second.PrivateKeySize()
+}
+
+func (c *combiner) CiphertextSize() int {
+	return c.first.CiphertextSize() + c.second.CiphertextSize()
+}
+
+func (c *combiner) SharedSecretSize() int { return combinedSharedSecretSize }
+
+func (c *combiner) GenerateKey(rand io.Reader) ([]byte, []byte, error) {
+	rand = orDefault(rand)
+	pk1, sk1, err := c.first.GenerateKey(rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	pk2, sk2, err := c.second.GenerateKey(rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(pk1, pk2...), append(sk1, sk2...), nil
+}
+
+func (c *combiner) Encapsulate(rand io.Reader, pk []byte) ([]byte, []byte, error) {
+	if len(pk) != c.PublicKeySize() {
+		return nil, nil, ErrInvalidPublicKeyLength
+	}
+	rand = orDefault(rand)
+	pk1, pk2 := pk[:c.first.PublicKeySize()], pk[c.first.PublicKeySize():]
+	ct1, ss1, err := c.first.Encapsulate(rand, pk1)
+	if err != nil {
+		return nil, nil, err
+	}
+	ct2, ss2, err := c.second.Encapsulate(rand, pk2)
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(ct1, ct2...), c.combine(ss1, ss2, ct1, ct2, pk1, pk2), nil
+}
+
+func (c *combiner) Decapsulate(sk, ct []byte) ([]byte, error) {
+	if len(sk) != c.PrivateKeySize() {
+		return nil, ErrInvalidPrivateKeyLength
+	}
+	if len(ct) != c.CiphertextSize() {
+		return nil, ErrInvalidCiphertextLength
+	}
+	sk1, sk2 := sk[:c.first.PrivateKeySize()], sk[c.first.PrivateKeySize():]
+	ct1, ct2 := ct[:c.first.CiphertextSize()], ct[c.first.CiphertextSize():]
+	ss1, err := c.first.Decapsulate(sk1, ct1)
+	if err != nil {
+		return nil, err
+	}
+	ss2, err := c.second.Decapsulate(sk2, ct2)
+	if err != nil {
+		return nil, err
+	}
+	pk1, err := c.first.PublicKey(sk1)
+	if err != nil {
+		return nil, err
+	}
+	pk2, err := c.second.PublicKey(sk2)
+	if err != nil {
+		return nil, err
+	}
+	return c.combine(ss1, ss2, ct1, ct2, pk1, pk2), nil
+}
+
+func (c *combiner) PublicKey(sk []byte) ([]byte, error) {
+	if len(sk) != c.PrivateKeySize() {
+		return nil, ErrInvalidPrivateKeyLength
+	}
+	pk1, err := c.first.PublicKey(sk[:c.first.PrivateKeySize()])
+	if err != nil {
+		return nil, err
+	}
+	pk2, err := c.second.PublicKey(sk[c.first.PrivateKeySize():])
+	if err != nil {
+		return nil, err
+	}
+	return append(pk1, pk2...), nil
+}
+
+func (c *combiner) combine(ss1, ss2, ct1, ct2, pk1, pk2 []byte) []byte {
+	ss := make([]byte, combinedSharedSecretSize)
+	switch c.kdf {
+	case KDFKEMCombiner:
+		h := sha3.New256()
+		for _, b := range [][]byte{ss1, ss2, ct1, ct2, pk1, pk2, c.label} {
+			h.Write(b)
+		}
+		h.Sum(ss[:0])
+	case KDFConcatHKDF:
+		var info []byte
+		for _, b := range [][]byte{c.label, ct1, ct2, pk1, pk2} {
+			info = append(info, b...)
+		}
+		r := hkdf.New(sha256.New, append(append([]byte{}, ss1...), ss2...), nil, info)
+		if _, err := io.ReadFull(r, ss); err != nil {
+			// unreachable: HKDF-SHA256 can expand to 255*32 bytes
+			panic(err)
+		}
+	}
+	return ss
+}
@@ -0,0 +1,154 @@
+package hybrid
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"testing"
+
+	kyber "github.com/depressi0n/kyber-go"
+)
+
+func testKEM(t *testing.T, kem KEM) {
+	pk, sk, err := kem.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pk) != kem.PublicKeySize() || len(sk) != kem.PrivateKeySize() {
+		t.Fatalf("%s: got %d byte public key, %d byte private key", kem.Name(), len(pk), len(sk))
+	}
+	pk2, err := kem.PublicKey(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pk, pk2) {
+		t.Errorf("%s: recovered public key differs", kem.Name())
+	}
+
+	ct, ss, err := kem.Encapsulate(nil, pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ct) != kem.CiphertextSize() || len(ss) != kem.SharedSecretSize() {
+		t.Fatalf("%s: got %d byte ciphertext, %d byte shared secret", kem.Name(), len(ct), len(ss))
+	}
+	ss2, err := kem.Decapsulate(sk, ct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ss, ss2) {
+		t.Errorf("%s: shared secrets differ", kem.Name())
+	}
+
+	if _, _, err := kem.Encapsulate(nil, pk[1:]); err == nil {
+		t.Errorf("%s: Encapsulate accepted a short public key", kem.Name())
+	}
+	if _, err := kem.Decapsulate(sk, ct[1:]); err == nil {
+		t.Errorf("%s: Decapsulate accepted a short ciphertext", kem.Name())
+	}
+}
+
+func mustECDH(t *testing.T, curve ecdh.Curve) KEM {
+	kem, err := ECDH(curve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kem
+}
+
+func mustCombiner(t *testing.T, first, second KEM, kdf KDF, label string) KEM {
+	kem, err := NewCombiner(first, second, kdf, label)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kem
+}
+
+func TestKEMs(t *testing.T) {
+	for _, kem := range []KEM{
+		Kyber(kyber.NewParameters(3)),
+		Kyber(kyber.NewMLKEMParameters(4)),
+		mustECDH(t, ecdh.X25519()),
+		mustECDH(t, ecdh.P256()),
+		mustECDH(t, ecdh.P384()),
+		mustECDH(t, ecdh.P521()),
+		X448(),
+		P256Kyber768(),
+		X448Kyber1024(),
+		mustCombiner(t, mustECDH(t, ecdh.X25519()), Kyber(kyber.NewParameters(2)), KDFConcatHKDF, "X25519-Kyber512"),
+	} {
+		testKEM(t, kem)
+	}
+}
+
+func TestECDHUnsupportedCurve(t *testing.T) {
+	if _, err := ECDH(nil); err != ErrUnsupportedCurve {
+		t.Errorf("ECDH(nil): got %v", err)
+	}
+}
+
+func TestCombinerBinding(t *testing.T) {
+	first := Kyber(kyber.NewMLKEMParameters(3))
+	second := mustECDH(t, ecdh.P256())
+	for _, kdf := range []KDF{KDFConcatHKDF, KDFKEMCombiner} {
+		kem := mustCombiner(t, first, second, kdf, "test")
+		pk, sk, err := kem.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ct, ss, err := kem.Encapsulate(nil, pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the label separates otherwise identical combinations
+		other := mustCombiner(t, first, second, kdf, "other")
+		ss2, err := other.Decapsulate(sk, ct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(ss, ss2) {
+			t.Errorf("kdf %d: label does not change the shared secret", kdf)
+		}
+
+		// both public keys are bound
+		c := kem.(*combiner)
+		n := first.PublicKeySize()
+		ss1, ss2 := make([]byte, 32), make([]byte, 32)
+		base := c.combine(ss1, ss2, ct[:1], ct[1:2], pk[:n], pk[n:])
+		for i, keys := range [][2][]byte{{pk[:n-1], pk[n:]}, {pk[:n], pk[n+1:]}} {
+			if bytes.Equal(base, c.combine(ss1, ss2, ct[:1], ct[1:2], keys[0], keys[1])) {
+				t.Errorf("kdf %d: public key %d is not bound", kdf, i+1)
+			}
+		}
+
+		// a changed Kyber ciphertext gives an implicit-rejection secret
+		bad := append([]byte{}, ct...)
+		bad[0] ^= 1
+		ss3, err := kem.Decapsulate(sk, bad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(ss, ss3) {
+			t.Errorf("kdf %d: tampered ciphertext gave the same shared secret", kdf)
+		}
+	}
+}
+
+func TestCombinerUnsupportedKDF(t *testing.T) {
+	first, second := Kyber(kyber.NewMLKEMParameters(3)), X448()
+	for _, kdf := range []KDF{-1, KDFKEMCombiner + 1} {
+		if _, err := NewCombiner(first, second, kdf, "test"); err != ErrUnsupportedKDF {
+			t.Errorf("kdf %d: got %v", kdf, err)
+		}
+	}
+}
+
+func TestX448LowOrder(t *testing.T) {
+	_, sk, err := X448().GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := X448().Decapsulate(sk, make([]byte, x448Size)); err == nil {
+		t.Error("X448 accepted the zero point")
+	}
+}
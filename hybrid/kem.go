@@ -0,0 +1,235 @@
+// Package hybrid composes key encapsulation mechanisms: Kyber/ML-KEM,
+// ECDH groups used as KEMs, and combiners joining any two of them.
+package hybrid
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"errors"
+	"io"
+
+	kyber "github.com/depressi0n/kyber-go"
+)
+
+var (
+	ErrInvalidPublicKeyLength  = errors.New("hybrid: invalid public key length")
+	ErrInvalidPrivateKeyLength = errors.New("hybrid: invalid private key length")
+	ErrInvalidCiphertextLength = errors.New("hybrid: invalid ciphertext length")
+	ErrUnsupportedCurve        = errors.New("hybrid: unsupported curve")
+	ErrUnsupportedKDF          = errors.New("hybrid: unsupported KDF")
+)
+
+// KEM is a key encapsulation mechanism over serialized keys.
+// A nil rand reader means crypto/rand.
+type KEM interface {
+	Name() string
+
+	PublicKeySize() int
+	PrivateKeySize() int
+	CiphertextSize() int
+	SharedSecretSize() int
+
+	GenerateKey(rand io.Reader) (pk, sk []byte, err error)
+	Encapsulate(rand io.Reader, pk []byte) (ct, ss []byte, err error)
+	Decapsulate(sk, ct []byte) (ss []byte, err error)
+	// PublicKey recovers the public key of sk
+	PublicKey(sk []byte) ([]byte, error)
+}
+
+func orDefault(r io.Reader) io.Reader {
+	if r == nil {
+		return rand.Reader
+	}
+	return r
+}
+
+type kyberKEM struct {
+	params *kyber.Parameters
+}
+
+// Kyber returns the KEM of a Kyber or ML-KEM parameter set.
+// Private keys use the expanded KYBER_SECRETKEYBYTES encoding.
+func Kyber(params *kyber.Parameters) KEM {
+	return &kyberKEM{params}
+}
+
+func (k *kyberKEM) Name() string { return k.params.KYBER_NAME }
+
+func (k *kyberKEM) PublicKeySize() int { return k.params.KYBER_PUBLICKEYBYTES }
+
+func (k *kyberKEM) PrivateKeySize() int { return k.params.KYBER_SECRETKEYBYTES }
+
+func (k *kyberKEM) CiphertextSize() int { return k.params.KYBER_CIPHERTEXTBYTES }
+
+func (k *kyberKEM) SharedSecretSize() int { return kyber.KYBER_SSBYTES }
+
+func (k *kyberKEM) GenerateKey(rand io.Reader) ([]byte, []byte, error) {
+	return kyber.Crypto_kem_keypair_rand(k.params, orDefault(rand))
+}
+
+func (k *kyberKEM) Encapsulate(rand io.Reader, pk []byte) ([]byte, []byte, error) {
+	return kyber.Crypto_kem_enc_rand(k.params, pk, orDefault(rand))
+}
+
+func (k *kyberKEM) Decapsulate(sk, ct []byte) ([]byte, error) {
+	return kyber.Crypto_kem_dec_checked(k.params, ct, sk)
+}
+
+func (k *kyberKEM) PublicKey(sk []byte) ([]byte, error) {
+	if len(sk) != k.params.KYBER_SECRETKEYBYTES {
+		return nil, ErrInvalidPrivateKeyLength
+	}
+	pk := make([]byte, k.params.KYBER_PUBLICKEYBYTES)
+	copy(pk, sk[k.params.KYBER_INDCPA_SECRETKEYBYTES:])
+	return pk, nil
+}
+
+// ECDH as a KEM: the ciphertext is an ephemeral public key and the
+// shared secret the raw Diffie-Hellman output, as in TLS 1.3 hybrids.
+// It is meant to be combined, not used on its own.
+type ecdhKEM struct {
+	curve                 ecdh.Curve
+	name                  string
+	pointSize, scalarSize int
+}
+
+var (
+	ecdhX25519 = &ecdhKEM{ecdh.X25519(), "X25519", 32, 32}
+	ecdhP256   = &ecdhKEM{ecdh.P256(), "P-256", 65, 32}
+	ecdhP384   = &ecdhKEM{ecdh.P384(), "P-384", 97, 48}
+	ecdhP521   = &ecdhKEM{ecdh.P521(), "P-521", 133, 66}
+)
+
+// ECDH returns the KEM of a crypto/ecdh curve, or ErrUnsupportedCurve
+// for curves other than X25519, P-256, P-384 and P-521
+func ECDH(curve ecdh.Curve) (KEM, error) {
+	switch curve {
+	case ecdh.X25519():
+		return ecdhX25519, nil
+	case ecdh.P256():
+		return ecdhP256, nil
+	case ecdh.P384():
+		return ecdhP384, nil
+	case ecdh.P521():
+		return ecdhP521, nil
+	}
+	return nil, ErrUnsupportedCurve
+}
+
+func (k *ecdhKEM) Name() string { return k.name }
+
+func (k *ecdhKEM) PublicKeySize() int { return k.pointSize }
+
+func (k *ecdhKEM) PrivateKeySize() int { return k.scalarSize }
+
+func (k *ecdhKEM) CiphertextSize() int { return k.pointSize }
+
+func (k *ecdhKEM) SharedSecretSize() int { return k.scalarSize }
+
+func (k *ecdhKEM) GenerateKey(rand io.Reader) ([]byte, []byte, error) {
+	sk, err := k.curve.GenerateKey(orDefault(rand))
+	if err != nil {
+		return nil, nil, err
+	}
+	return sk.PublicKey().Bytes(), sk.Bytes(), nil
+}
+
+func (k *ecdhKEM) Encapsulate(rand io.Reader, pk []byte) ([]byte, []byte, error) {
+	pub, err := k.curve.NewPublicKey(pk)
+	if err != nil {
+		return nil, nil, err
+	}
+	eph, err := k.curve.GenerateKey(orDefault(rand))
+	if err != nil {
+		return nil, nil, err
+	}
+	ss, err := eph.ECDH(pub)
+	if err != nil {
+		return nil, nil, err
+	}
+	return eph.PublicKey().Bytes(), ss, nil
+}
+
+func (k *ecdhKEM) Decapsulate(sk, ct []byte) ([]byte, error) {
+	priv, err := k.curve.NewPrivateKey(sk)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := k.curve.NewPublicKey(ct)
+	if err != nil {
+		return nil, err
+	}
+	return priv.ECDH(pub)
+}
+
+func (k *ecdhKEM) PublicKey(sk []byte) ([]byte, error) {
+	priv, err := k.curve.NewPrivateKey(sk)
+	if err != nil {
+		return nil, err
+	}
+	return priv.PublicKey().Bytes(), nil
+}
+
+type x448KEM struct{}
+
+// X448 returns the X448 (RFC 7748) Diffie-Hellman function as a KEM
+func X448() KEM { return x448KEM{} }
+
+func (x448KEM) Name() string { return "X448" }
+
+func (x448KEM) PublicKeySize() int { return x448Size }
+
+func (x448KEM) PrivateKeySize() int { return x448Size }
+
+func (x448KEM) CiphertextSize() int { return x448Size }
+
+func (x448KEM) SharedSecretSize() int { return x448Size }
+
+func (k x448KEM) GenerateKey(rand io.Reader) ([]byte, []byte, error) {
+	sk := make([]byte, x448Size)
+	if _, err := io.ReadFull(orDefault(rand), sk); err != nil {
+		return nil, nil, err
+	}
+	pk, err := k.PublicKey(sk)
+	return pk, sk, err
+}
+
+func (k x448KEM) Encapsulate(rand io.Reader, pk []byte) ([]byte, []byte, error) {
+	if len(pk) != x448Size {
+		return nil, nil, ErrInvalidPublicKeyLength
+	}
+	ct, esk, err := k.GenerateKey(rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	ss, err := k.Decapsulate(esk, pk)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ct, ss, nil
+}
+
+func (x448KEM) Decapsulate(sk, ct []byte) ([]byte, error) {
+	if len(sk) != x448Size {
+		return nil, ErrInvalidPrivateKeyLength
+	}
+	if len(ct) != x448Size {
+		return nil, ErrInvalidCiphertextLength
+	}
+	ss := make([]byte, x448Size)
+	x448ScalarMult(ss, sk, ct)
+	// reject low order points, as crypto/ecdh does for X25519
+	if x448IsZero(ss) {
+		return nil, errors.New("hybrid: X448 shared secret is all zeroes")
+	}
+	return ss, nil
+}
+
+func (x448KEM) PublicKey(sk []byte) ([]byte, error) {
+	if len(sk) != x448Size {
+		return nil, ErrInvalidPrivateKeyLength
+	}
+	pk := make([]byte, x448Size)
+	x448ScalarMult(pk, sk, x448Basepoint[:])
+	return pk, nil
+}
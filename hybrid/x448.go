@@ -0,0 +1,230 @@
+package hybrid
+
+import "crypto/subtle"
+
+// X448 (RFC 7748) over GF(2^448 - 2^224 - 1), which is neither in
+// the standard library nor in golang.org/x/crypto. Field elements are
+// 16 limbs of 28 bits so that products accumulate in uint64 without
+// overflow.
+//
+// Constant time: no branch or memory index depends on secret data. The
+// scalar bits only select the conditional swaps of the ladder, done
+// with masks in feSwap; the final subtraction of p in feToBytes selects
+// its result with the borrow mask; the branches in feInvert and the
+// loop bounds depend on public constants only. Limb arithmetic uses
+// uint64 add, shift, mask and multiply, which are constant time on the
+// platforms Go supports.
+//
+// Limb bounds: carry, and so feAdd, feSub and feMul, return limbs of at
+// most limbMask (2^28 - 1); the inputs of every field operation are such
+// outputs, the small constant a24 or feFromBytes limbs (at most limbMask).
+
+const (
+	x448Size   = 56
+	limbBits   = 28
+	limbMask   = 1<<limbBits - 1
+	numLimbs   = 16
+	x448A24    = 39081
+	x448Scalar = 448
+)
+
+type fe [numLimbs]uint64
+
+// carry propagates limb overflows, folding 2^448 = 2^224 + 1. For
+// limbs below 2^63 the first pass leaves limbs 0 and 8 below
+// limbMask + 2^36 and the rest at most limbMask; the second pass then
+// carries at most 1 between limbs and out of limb 15, and if it does
+// carry out of limb 15 that limb is left at 0, so the third pass
+// cannot carry out again. Every limb ends at most limbMask
+func (v *fe) carry() {
+	for pass := 0; pass < 3; pass++ {
+		for i := 0; i < numLimbs-1; i++ {
+			v[i+1] += v[i] >> limbBits
+			v[i] &= limbMask
+		}
+		c := v[numLimbs-1] >> limbBits
+		v[numLimbs-1] &= limbMask
+		v[0] += c
+		v[numLimbs/2] += c
+	}
+}
+
+func feAdd(out, a, b *fe) {
+	for i := range out {
+		out[i] = a[i] + b[i]
+	}
+	out.carry()
+}
+
+// feSub computes a - b + 4p so limbs never go negative: the limbs
+// of 4p are 4*limbMask (4*limbMask - 4 for limb 8), above any limb
+// of b
+func feSub(out, a, b *fe) {
+	for i := range out {
+		p4 := uint64(4 * limbMask)
+		if i == numLimbs/2 {
+			p4 -= 4
+		}
+		out[i] = a[i] + p4 - b[i]
+	}
+	out.carry()
+}
+
+// feMul multiplies limbs of at most limbMask. Each of the 31 product
+// columns is a sum of at most 16 products below 2^56, so below 2^60;
+// folding 2^448 = 2^224 + 1 first lifts columns 16-22 below 2^61 and
+// then leaves columns 0-15 below 2^62, without overflow. One carry pass
+// brings limbs 0 and 8 below limbMask + 2^36 and carry does the rest
+func feMul(out, a, b *fe) {
+	var c [2*numLimbs - 1]uint64
+	for i := 0; i < numLimbs; i++ {
+		for j := 0; j < numLimbs; j++ {
+			c[i+j] += a[i] * b[j]
+		}
+	}
+	for k := 2*numLimbs - 2; k >= numLimbs; k-- {
+		c[k-numLimbs] += c[k]
+		c[k-numLimbs/2] += c[k]
+	}
+	copy(out[:], c[:numLimbs])
+	// limbs may be up to 2^62 here, so carry until they are small
+	for i := 0; i < numLimbs-1; i++ {
+		out[i+1] += out[i] >> limbBits
+		out[i] &= limbMask
+	}
+	top := out[numLimbs-1] >> limbBits
+	out[numLimbs-1] &= limbMask
+	out[0] += top
+	out[numLimbs/2] += top
+	out.carry()
+}
+
+func feSquare(out, a *fe) { feMul(out, a, a) }
+
+// feInvert computes a^(p-2), p-2 = 2^448 - 2^224 - 3
+func feInvert(out, a *fe) {
+	r := fe{1}
+	for i := x448Scalar - 1; i >= 0; i-- {
+		feSquare(&r, &r)
+		if i != 224 && i != 1 {
+			feMul(&r, &r, a)
+		}
+	}
+	*out = r
+}
+
+func feSwap(swap uint64, a, b *fe) {
+	mask := -swap
+	for i := range a {
+		t := mask & (a[i] ^ b[i])
+		a[i] ^= t
+		b[i] ^= t
+	}
+}
+
+func feFromBytes(out *fe, b []byte) {
+	var acc uint64
+	var bits uint
+	j := 0
+	for _, x := range b[:x448Size] {
+		acc |= uint64(x) << bits
+		bits += 8
+		if bits >= limbBits {
+			out[j] = acc & limbMask
+			acc >>= limbBits
+			bits -= limbBits
+			j++
+		}
+	}
+}
+
+// feToBytes writes the canonical encoding of v
+func feToBytes(b []byte, v *fe) {
+	t := *v
+	t.carry()
+	// t < 2p, subtract p once if t >= p
+	var p fe
+	for i := range p {
+		p[i] = limbMask
+	}
+	p[numLimbs/2]--
+	var d fe
+	var borrow uint64
+	for i := range d {
+		x := t[i] - p[i] - borrow
+		borrow = x >> 63
+		d[i] = x & limbMask
+	}
+	feSwap(1-borrow, &t, &d)
+
+	var acc uint64
+	var bits uint
+	j := 0
+	for _, x := range t {
+		acc |= x << bits
+		bits += limbBits
+		for bits >= 8 {
+			b[j] = byte(acc)
+			acc >>= 8
+			bits -= 8
+			j++
+		}
+	}
+}
+
+// x448ScalarMult sets dst to the X448 function of scalar and point
+func x448ScalarMult(dst, scalar, point []byte) {
+	var k [x448Size]byte
+	copy(k[:], scalar)
+	k[0] &= 252
+	k[x448Size-1] |= 128
+
+	var x1, x2, z2, x3, z3 fe
+	feFromBytes(&x1, point)
+	x2[0] = 1
+	x3 = x1
+	z3[0] = 1
+	a24 := fe{x448A24}
+
+	var a, aa, b, bb, e, c, d, da, cb fe
+	var swap uint64
+	for t := x448Scalar - 1; t >= 0; t-- {
+		kt := uint64(k[t/8]>>(t%8)) & 1
+		swap ^= kt
+		feSwap(swap, &x2, &x3)
+		feSwap(swap, &z2, &z3)
+		swap = kt
+
+		feAdd(&a, &x2, &z2)
+		feSquare(&aa, &a)
+		feSub(&b, &x2, &z2)
+		feSquare(&bb, &b)
+		feSub(&e, &aa, &bb)
+		feAdd(&c, &x3, &z3)
+		feSub(&d, &x3, &z3)
+		feMul(&da, &d, &a)
+		feMul(&cb, &c, &b)
+
+		feAdd(&x3, &da, &cb)
+		feSquare(&x3, &x3)
+		feSub(&z3, &da, &cb)
+		feSquare(&z3, &z3)
+		feMul(&z3, &z3, &x1)
+		feMul(&x2, &aa, &bb)
+		feMul(&z2, &a24, &e)
+		feAdd(&z2, &z2, &aa)
+		feMul(&z2, &z2, &e)
+	}
+	feSwap(swap, &x2, &x3)
+	feSwap(swap, &z2, &z3)
+
+	feInvert(&z2, &z2)
+	feMul(&x2, &x2, &z2)
+	feToBytes(dst, &x2)
+}
+
+var x448Basepoint = [x448Size]byte{5}
+
+func x448IsZero(b []byte) bool {
+	return subtle.ConstantTimeCompare(b, make([]byte, len(b))) == 1
+}
@@ -0,0 +1,142 @@
+package hybrid
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+// x448Reference is a straightforward math/big transcription of the
+// RFC 7748 ladder, used to cross-check the limb arithmetic
+func x448Reference(scalar, point []byte) []byte {
+	p := new(big.Int).Lsh(big.NewInt(1), 448)
+	p.Sub(p, new(big.Int).Lsh(big.NewInt(1), 224))
+	p.Sub(p, big.NewInt(1))
+
+	le := func(b []byte) *big.Int {
+		r := make([]byte, len(b))
+		for i := range b {
+			r[len(b)-1-i] = b[i]
+		}
+		return new(big.Int).SetBytes(r)
+	}
+	k := append([]byte{}, scalar...)
+	k[0] &= 252
+	k[55] |= 128
+	kk := le(k)
+	x1 := new(big.Int).Mod(le(point), p)
+
+	x2, z2 := big.NewInt(1), big.NewInt(0)
+	x3, z3 := new(big.Int).Set(x1), big.NewInt(1)
+	mod := func(x *big.Int) *big.Int { return x.Mod(x, p) }
+	for t := 447; t >= 0; t-- {
+		if kk.Bit(t) == 1 {
+			x2, x3 = x3, x2
+			z2, z3 = z3, z2
+		}
+		a := mod(new(big.Int).Add(x2, z2))
+		aa := mod(new(big.Int).Mul(a, a))
+		b := mod(new(big.Int).Sub(x2, z2))
+		bb := mod(new(big.Int).Mul(b, b))
+		e := mod(new(big.Int).Sub(aa, bb))
+		c := mod(new(big.Int).Add(x3, z3))
+		d := mod(new(big.Int).Sub(x3, z3))
+		da := mod(new(big.Int).Mul(d, a))
+		cb := mod(new(big.Int).Mul(c, b))
+		x3 = mod(new(big.Int).Add(da, cb))
+		x3 = mod(x3.Mul(x3, x3))
+		z3 = mod(new(big.Int).Sub(da, cb))
+		z3 = mod(z3.Mul(z3, z3))
+		z3 = mod(z3.Mul(z3, x1))
+		x2 = mod(new(big.Int).Mul(aa, bb))
+		z2 = mod(new(big.Int).Mul(big.NewInt(x448A24), e))
+		z2 = mod(z2.Add(z2, aa))
+		z2 = mod(z2.Mul(z2, e))
+		if kk.Bit(t) == 1 {
+			x2, x3 = x3, x2
+			z2, z3 = z3, z2
+		}
+	}
+	z2.ModInverse(z2, p)
+	x2 = mod(x2.Mul(x2, z2))
+	out := make([]byte, x448Size)
+	x2.FillBytes(out)
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+func TestX448Vectors(t *testing.T) {
+	// RFC 7748, Section 5.2
+	for _, v := range []struct{ scalar, u, out string }{
+		{
+			"3d262fddf9ec8e88495266fea19a34d28882acef045104d0d1aae121700a779c984c24f8cdd78fbff44943eba368f54b29259a4f1c600ad3",
+			"06fce640fa3487bfda5f6cf2d5263f8aad88334cbd07437f020f08f9814dc031ddbdc38c19c6da2583fa5429db94ada18aa7a7fb4ef8a086",
+			"ce3e4ff95a60dc6697da1db1d85e6afbdf79b50a2412d7546d5f239fe14fbaadeb445fc66a01b0779d98223961111e21766282f73dd96b6f",
+		},
+		{
+			"203d494428b8399352665ddca42f9de8fef600908e0d461cb021f8c538345dd77c3e4806e25f46d3315c44e0a5b4371282dd2c8d5be3095f",
+			"0fbcc2f993cd56d3305b0b7d9e55d4c1a8fb5dbb52f8e9a1e9b6201b165d015894e56c4d3570bee52fe205e28a78b91cdfbde71ce8d157db",
+			"884a02576239ff7a2f2f63b2db6a9ff37047ac13568e1e30fe63c4a7ad1b3ee3a5700df34321d62077e63633c575c1c954514e99da7c179d",
+		},
+	} {
+		scalar, _ := hex.DecodeString(v.scalar)
+		u, _ := hex.DecodeString(v.u)
+		want, _ := hex.DecodeString(v.out)
+		got := make([]byte, x448Size)
+		x448ScalarMult(got, scalar, u)
+		if !bytes.Equal(got, want) {
+			t.Errorf("X448 = %x, want %x", got, want)
+		}
+	}
+}
+
+func TestX448Iterated(t *testing.T) {
+	// RFC 7748, Section 5.2: k = u = 5, then k, u = X448(k, u), k
+	k := make([]byte, x448Size)
+	u := make([]byte, x448Size)
+	k[0], u[0] = 5, 5
+	for i := 1; i <= 1000; i++ {
+		r := make([]byte, x448Size)
+		x448ScalarMult(r, k, u)
+		k, u = r, k
+		var want string
+		switch i {
+		case 1:
+			want = "3f482c8a9f19b01e6c46ee9711d9dc14fd4bf67af30765c2ae2b846a4d23a8cd0db897086239492caf350b51f833868b9bc2b3bca9cf4113"
+		case 1000:
+			want = "aa3b4749d55b9daf1e5b00288826c467274ce3ebbdd5c17b975e09d4af6c67cf10d087202db88286e2b79fceea3ec353ef54faa26e219f38"
+		default:
+			continue
+		}
+		if got := hex.EncodeToString(k); got != want {
+			t.Fatalf("after %d iterations: got %s, want %s", i, got, want)
+		}
+	}
+}
+
+func TestX448Reference(t *testing.T) {
+	scalar := make([]byte, x448Size)
+	point := make([]byte, x448Size)
+	for i := 0; i < 8; i++ {
+		rand.Read(scalar)
+		rand.Read(point)
+		got := make([]byte, x448Size)
+		x448ScalarMult(got, scalar, point)
+		if want := x448Reference(scalar, point); !bytes.Equal(got, want) {
+			t.Fatalf("X448(%x, %x) = %x, want %x", scalar, point, got, want)
+		}
+	}
+	// non-canonical u-coordinates above p reduce modulo p
+	for i := range point {
+		point[i] = 0xff
+	}
+	got := make([]byte, x448Size)
+	x448ScalarMult(got, scalar, point)
+	if want := x448Reference(scalar, point); !bytes.Equal(got, want) {
+		t.Fatalf("non-canonical u: got %x, want %x", got, want)
+	}
+}
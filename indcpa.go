@@ -1,5 +1,7 @@
 package kyber
 
+import "io"
+
 const GEN_MATRIX_NBLOCKS = ((12*KYBER_N/8*(1<<12)/KYBER_Q + XOF_BLOCKBYTES) / XOF_BLOCKBYTES)
 
 /*************************************************
@@ -242,51 +244,41 @@ func gen_at(params *Parameters, seed []byte) []*polyvec {
 *                (of length KYBER_INDCPA_SECRETKEYBYTES bytes)
 **************************************************/
 func Indcpa_keypair(params *Parameters) ([]byte, []byte) {
-	var buf [2 * KYBER_SYMBYTES]byte
-	//var publicseed = buf[:KYBER_SYMBYTES]
-	publicseed := buf[:KYBER_SYMBYTES]
-	noiseseed := buf[KYBER_SYMBYTES:]
-
-	skpv := newPolyvec(params)
-	e := newPolyvec(params)
-	pkpv := newPolyvec(params)
-
-	copy(publicseed, randombytes(KYBER_SYMBYTES))
-	buf = hash_g(publicseed, KYBER_SYMBYTES) //hash_g(buf, buf, KYBER_SYMBYTES);
-	a := gen_a(params, publicseed)
-	nonce := byte(0)
-	for i := 0; i < params.KYBER_K; i++ {
-		poly_getnoise_eta1(params, &skpv.vec[i], noiseseed, nonce)
-		nonce++
-	}
-	for i := 0; i < params.KYBER_K; i++ {
-		poly_getnoise_eta1(params, &e.vec[i], noiseseed, nonce)
-		nonce++
-	}
-
-	polyvec_ntt(params, skpv)
-	polyvec_ntt(params, e)
+	return Indcpa_keypair_with_recovery(params, randombytes(KYBER_SYMBYTES))
+}
 
-	// matrix-vector multiplication
-	for i := 0; i < params.KYBER_K; i++ {
-		polyvec_basemul_acc_montgomery(params, &pkpv.vec[i], a[i], skpv)
-		poly_tomont(&pkpv.vec[i])
+/*************************************************
+* Name:        indcpa_keypair_rand
+*
+* Description: Generates public and private key for the CPA-secure
+*              public-key encryption scheme underlying Kyber,
+*              drawing the seed from rand
+*
+* Arguments:   - params *Parameters: Kem parameters struct
+*              - rand io.Reader: source of randomness
+*
+* Returns      - pk []byte: ouptput public key
+*                (of length KYBER_INDCPA_PUBLICKEYBYTES bytes)
+*              - sk []byte: ouptput private key
+*                (of length KYBER_INDCPA_SECRETKEYBYTES bytes)
+*              - err error: non-nil if rand fails
+**************************************************/
+func Indcpa_keypair_rand(params *Parameters, rand io.Reader) ([]byte, []byte, error) {
+	seed, err := randombytes_from(rand, KYBER_SYMBYTES)
+	if err != nil {
+		return nil, nil, err
 	}
-
-	polyvec_add(params, pkpv, pkpv, e)
-	polyvec_reduce(params, pkpv)
-
-	sk := pack_sk(params, skpv)
-	pk := pack_pk(params, pkpv, publicseed)
-
-	return pk, sk
+	pk, sk := Indcpa_keypair_with_recovery(params, seed)
+	return pk, sk, nil
 }
 
 /*************************************************
 * Name:        indcpa_keypair_with_recovery
 *
 * Description: Generates public and private key for the CPA-secure
-*              public-key encryption scheme underlying Kyber with input seeds
+*              public-key encryption scheme underlying Kyber with input seeds.
+*              In ML-KEM mode the seed is domain separated by KYBER_K
+*              as in K-PKE.KeyGen of FIPS 203
 *
 * Arguments:   - params *Parameters: Kem parameters struct
 *              - seed []byte: input seed
@@ -308,7 +300,11 @@ func Indcpa_keypair_with_recovery(params *Parameters, seed []byte) ([]byte, []by
 	pkpv := newPolyvec(params)
 
 	copy(publicseed, seed[:KYBER_SYMBYTES])
-	buf = hash_g(publicseed, KYBER_SYMBYTES) //hash_g(buf, buf, KYBER_SYMBYTES);
+	if params.KYBER_MODE == KYBER_MODE_MLKEM {
+		buf = hash_g(append(publicseed[:KYBER_SYMBYTES:KYBER_SYMBYTES], byte(params.KYBER_K)), KYBER_SYMBYTES+1)
+	} else {
+		buf = hash_g(publicseed, KYBER_SYMBYTES) //hash_g(buf, buf, KYBER_SYMBYTES);
+	}
 
 	a := gen_a(params, publicseed)
 
@@ -444,3 +440,60 @@ func Indcpa_dec(params *Parameters, c []byte, sk []byte) []byte { //uint8_t m[KY
 	poly_tomsg(m, mp)
 	return m
 }
+
+/*************************************************
+* Name:        indcpa_enc_checked
+*
+* Description: Same as indcpa_enc, but validates the lengths
+*              of all inputs instead of panicking on short input
+*
+* Arguments:   - params *Parameters: Kem parameters struct
+*              - m []byte: input message
+*                (of length KYBER_INDCPA_MSGBYTES bytes)
+*              - pk []byte: input public key
+*                (of length KYBER_INDCPA_PUBLICKEYBYTES bytes)
+*              - coins []byte: input random coins
+*                (of length KYBER_SYMBYTES)
+*
+* Returns      - c []byte: output ciphertext
+*                (of length KYBER_INDCPA_BYTES bytes)
+*              - err error: non-nil on bad input lengths
+**************************************************/
+func Indcpa_enc_checked(params *Parameters, m []byte, pk []byte, coins []byte) ([]byte, error) {
+	if len(m) != KYBER_INDCPA_MSGBYTES {
+		return nil, ErrInvalidMessageLength
+	}
+	if len(pk) != params.KYBER_INDCPA_PUBLICKEYBYTES {
+		return nil, ErrInvalidPublicKeyLength
+	}
+	if len(coins) != KYBER_SYMBYTES {
+		return nil, ErrInvalidCoinsLength
+	}
+	return Indcpa_enc(params, m, pk, coins), nil
+}
+
+/*************************************************
+* Name:        indcpa_dec_checked
+*
+* Description: Same as indcpa_dec, but validates the lengths
+*              of all inputs instead of panicking on short input
+*
+* Arguments:   - params *Parameters: Kem parameters struct
+*              - c []byte: input cipher text
+*                (of length KYBER_INDCPA_BYTES bytes)
+*              - sk []byte: input private key
+*                (of length KYBER_INDCPA_SECRETKEYBYTES bytes)
+*
+* Returns      - m []byte: output decrypted message
+*                (KYBER_INDCPA_MSGBYTES bytes)
+*              - err error: non-nil on bad input lengths
+**************************************************/
+func Indcpa_dec_checked(params *Parameters, c []byte, sk []byte) ([]byte, error) {
+	if len(c) != params.KYBER_INDCPA_BYTES {
+		return nil, ErrInvalidCiphertextLength
+	}
+	if len(sk) != params.KYBER_INDCPA_SECRETKEYBYTES {
+		return nil, ErrInvalidSecretKeyLength
+	}
+	return Indcpa_dec(params, c, sk), nil
+}
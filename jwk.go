@@ -0,0 +1,134 @@
+package kyber
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// JSON Web Key of the "AKP" (algorithm key pair) key type, with alg
+// set to KYBER_NAME and the raw key bytes base64url encoded
+type jsonWebKey struct {
+	Kty  string `json:"kty"`
+	Alg  string `json:"alg"`
+	Kid  string `json:"kid,omitempty"`
+	Pub  string `json:"pub"`
+	Priv string `json:"priv,omitempty"`
+}
+
+const jwkKeyType = "AKP"
+
+var b64 = base64.RawURLEncoding
+
+/*************************************************
+* Name:        (*PublicKey).Thumbprint
+*
+* Description: Computes the RFC 7638 JWK thumbprint of the key,
+*              the base64url SHA-256 of its required members
+*              {"alg","kty","pub"} in lexicographic order.
+*              It is used as kid of the JWK representation
+*
+* Returns      - kid string: output thumbprint
+**************************************************/
+func (pub *PublicKey) Thumbprint() string {
+	// The members only contain characters that need no JSON escaping
+	canonical := `{"alg":"` + pub.params.KYBER_NAME + `","kty":"` + jwkKeyType + `","pub":"` + b64.EncodeToString(pub.pk) + `"}`
+	sum := sha256.Sum256([]byte(canonical))
+	return b64.EncodeToString(sum[:])
+}
+
+// MarshalJSON encodes the public key as a JWK
+func (pub *PublicKey) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonWebKey{
+		Kty: jwkKeyType,
+		Alg: pub.params.KYBER_NAME,
+		Kid: pub.Thumbprint(),
+		Pub: b64.EncodeToString(pub.pk),
+	})
+}
+
+// UnmarshalJSON parses a JWK public key, applying crypto_kem_check_pk
+func (pub *PublicKey) UnmarshalJSON(data []byte) error {
+	var jwk jsonWebKey
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return err
+	}
+	params, err := jwkParameters(&jwk)
+	if err != nil {
+		return err
+	}
+	pk, err := b64.DecodeString(jwk.Pub)
+	if err != nil {
+		return err
+	}
+	key, err := ParsePublicKey(params, pk)
+	if err != nil {
+		return err
+	}
+	*pub = *key
+	return nil
+}
+
+// MarshalJSON encodes the private key as a JWK. priv holds the
+// seed if it is known and the expanded private key otherwise
+func (priv *PrivateKey) MarshalJSON() ([]byte, error) {
+	pub := priv.PublicKey()
+	sk := priv.seed
+	if sk == nil {
+		sk = priv.sk
+	}
+	return json.Marshal(jsonWebKey{
+		Kty:  jwkKeyType,
+		Alg:  priv.params.KYBER_NAME,
+		Kid:  pub.Thumbprint(),
+		Pub:  b64.EncodeToString(pub.pk),
+		Priv: b64.EncodeToString(sk),
+	})
+}
+
+// UnmarshalJSON parses a JWK private key in seed or expanded form
+// and checks that it matches the public key in pub
+func (priv *PrivateKey) UnmarshalJSON(data []byte) error {
+	var jwk jsonWebKey
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return err
+	}
+	params, err := jwkParameters(&jwk)
+	if err != nil {
+		return err
+	}
+	if jwk.Priv == "" {
+		return errors.New("kyber: JWK has no private key")
+	}
+	sk, err := b64.DecodeString(jwk.Priv)
+	if err != nil {
+		return err
+	}
+	pk, err := b64.DecodeString(jwk.Pub)
+	if err != nil {
+		return err
+	}
+
+	var key *PrivateKey
+	if len(sk) == KYBER_SEEDBYTES {
+		key, err = NewPrivateKeyFromSeed(params, sk)
+	} else {
+		key, err = ParsePrivateKey(params, sk)
+	}
+	if err != nil {
+		return err
+	}
+	if !key.PublicKey().Equal(&PublicKey{params: params, pk: pk}) {
+		return errors.New("kyber: JWK public key does not match private key")
+	}
+	*priv = *key
+	return nil
+}
+
+func jwkParameters(jwk *jsonWebKey) (*Parameters, error) {
+	if jwk.Kty != jwkKeyType {
+		return nil, errors.New("kyber: unsupported JWK key type " + jwk.Kty)
+	}
+	return ParametersByName(jwk.Alg)
+}
@@ -0,0 +1,85 @@
+package kyber
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func Test_JWK(t *testing.T) {
+	for _, params := range []*Parameters{NewMLKEMParameters(3), NewParameters(2)} {
+		priv, err := GenerateKey(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub := priv.PublicKey()
+
+		data, err := json.Marshal(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var jwk map[string]string
+		if err := json.Unmarshal(data, &jwk); err != nil {
+			t.Fatal(err)
+		}
+		if jwk["kty"] != "AKP" || jwk["alg"] != params.KYBER_NAME || jwk["kid"] != pub.Thumbprint() {
+			t.Errorf("%s: unexpected JWK %v", params.KYBER_NAME, jwk)
+		}
+		if _, ok := jwk["priv"]; ok {
+			t.Errorf("%s: public JWK contains priv", params.KYBER_NAME)
+		}
+
+		// RFC 7638: hash of the required members, sorted, without whitespace
+		required, _ := json.Marshal(map[string]string{"kty": jwk["kty"], "alg": jwk["alg"], "pub": jwk["pub"]})
+		sum := sha256.Sum256(required)
+		if pub.Thumbprint() != base64.RawURLEncoding.EncodeToString(sum[:]) {
+			t.Errorf("%s: thumbprint mismatch", params.KYBER_NAME)
+		}
+
+		var pub2 PublicKey
+		if err := json.Unmarshal(data, &pub2); err != nil {
+			t.Fatal(err)
+		}
+		if !pub.Equal(&pub2) {
+			t.Errorf("%s: public JWK round trip failed", params.KYBER_NAME)
+		}
+
+		data, err = json.Marshal(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var priv2 PrivateKey
+		if err := json.Unmarshal(data, &priv2); err != nil {
+			t.Fatal(err)
+		}
+		if !priv.Equal(&priv2) {
+			t.Errorf("%s: private JWK round trip failed", params.KYBER_NAME)
+		}
+
+		expanded, _ := ParsePrivateKey(params, priv.Bytes())
+		data, _ = json.Marshal(expanded)
+		var priv3 PrivateKey
+		if err := json.Unmarshal(data, &priv3); err != nil {
+			t.Fatal(err)
+		}
+		if !priv.Equal(&priv3) {
+			t.Errorf("%s: expanded private JWK round trip failed", params.KYBER_NAME)
+		}
+
+		other, _ := GenerateKey(params)
+		mixed := strings.Replace(string(data), jwk["pub"], base64.RawURLEncoding.EncodeToString(other.PublicKey().Bytes()), 1)
+		if err := json.Unmarshal([]byte(mixed), &priv3); err == nil {
+			t.Errorf("%s: mismatched pub accepted", params.KYBER_NAME)
+		}
+	}
+
+	var pub PublicKey
+	if err := json.Unmarshal([]byte(`{"kty":"OKP","alg":"ML-KEM-768","pub":""}`), &pub); err == nil {
+		t.Error("wrong kty accepted")
+	}
+	if err := json.Unmarshal([]byte(`{"kty":"AKP","alg":"ML-KEM-999","pub":""}`), &pub); err == nil {
+		t.Error("unknown alg accepted")
+	}
+}
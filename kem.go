@@ -1,7 +1,9 @@
 package kyber
 
 import (
+	"bytes"
 	"crypto/subtle"
+	"io"
 )
 
 /*************************************************
@@ -18,35 +20,24 @@ import (
 *                (KYBER_SECRETKEYBYTES bytes)
 **************************************************/
 func Crypto_kem_keypair(params *Parameters) ([]byte, []byte) {
-	pk := make([]byte, params.KYBER_PUBLICKEYBYTES)
-	sk := make([]byte, params.KYBER_SECRETKEYBYTES)
-
-	indcpa_pk, indcpa_sk := Indcpa_keypair(params)
-	copy(pk, indcpa_pk)
-	subtle.ConstantTimeCopy(1, sk[:params.KYBER_INDCPA_SECRETKEYBYTES], indcpa_sk[:])
-
-	pos := params.KYBER_INDCPA_SECRETKEYBYTES + params.KYBER_INDCPA_PUBLICKEYBYTES
-	copy(sk[params.KYBER_INDCPA_SECRETKEYBYTES:pos], indcpa_pk)
-
-	hpk := hash_h(pk, params.KYBER_PUBLICKEYBYTES)
-	copy(sk[pos:pos+KYBER_SYMBYTES], hpk[:])
-
+	d := randombytes(KYBER_SYMBYTES)
 	/* Value z for pseudo-random output on reject */
 	z := randombytes(KYBER_SYMBYTES)
-	subtle.ConstantTimeCopy(1, sk[(params.KYBER_SECRETKEYBYTES-KYBER_SYMBYTES):], z)
-
-	return pk, sk
+	return crypto_kem_keypair_derand(params, d, z)
 }
 
 /*************************************************
 * Name:        crypto_kem_keypair_with_recovery
 *
 * Description: Generates public and private key with input seed
-*              for CCA-secure Kyber key encapsulation mechanism
+*              for CCA-secure Kyber key encapsulation mechanism.
+*              A seed of KYBER_SEEDBYTES bytes (d||z) recovers the
+*              exact private key; with a seed of KYBER_SYMBYTES bytes
+*              only d is recovered and z is drawn at random
 *
 * Arguments:   - params *Parameters: Kem parameters struct
 *              - seed []byte: input seed
-*                (of length KYBER_SYMBYTES bytes)
+*                (of length KYBER_SYMBYTES or KYBER_SEEDBYTES bytes)
 *
 * Returns:     - pk []byte: output public key
 *                (KYBER_PUBLICKEYBYTES bytes)
@@ -54,11 +45,85 @@ func Crypto_kem_keypair(params *Parameters) ([]byte, []byte) {
 *                (KYBER_SECRETKEYBYTES bytes)
 **************************************************/
 func Crypto_kem_keypair_with_recovery(params *Parameters, seed []byte) ([]byte, []byte) {
+	if len(seed) >= KYBER_SEEDBYTES {
+		return crypto_kem_keypair_derand(params, seed[:KYBER_SYMBYTES], seed[KYBER_SYMBYTES:KYBER_SEEDBYTES])
+	}
+	/* Value z for pseudo-random output on reject */
+	z := randombytes(KYBER_SYMBYTES)
+	return crypto_kem_keypair_derand(params, seed, z)
+}
 
+/*************************************************
+* Name:        crypto_kem_keypair_from_seed
+*
+* Description: Deterministically regenerates public and private key
+*              from a seed d||z, byte-identical on every call
+*
+* Arguments:   - params *Parameters: Kem parameters struct
+*              - seed []byte: input seed
+*                (of length KYBER_SEEDBYTES bytes)
+*
+* Returns:     - pk []byte: output public key
+*                (KYBER_PUBLICKEYBYTES bytes)
+*              - sk []byte: output private key
+*                (KYBER_SECRETKEYBYTES bytes)
+*              - err error: ErrInvalidSeedLength on bad input
+**************************************************/
+func Crypto_kem_keypair_from_seed(params *Parameters, seed []byte) ([]byte, []byte, error) {
+	if len(seed) != KYBER_SEEDBYTES {
+		return nil, nil, ErrInvalidSeedLength
+	}
+	pk, sk := crypto_kem_keypair_derand(params, seed[:KYBER_SYMBYTES], seed[KYBER_SYMBYTES:])
+	return pk, sk, nil
+}
+
+/*************************************************
+* Name:        crypto_kem_keypair_rand
+*
+* Description: Generates public and private key
+*              for CCA-secure Kyber key encapsulation mechanism,
+*              drawing all randomness from rand
+*
+* Arguments:   - params *Parameters: Kem parameters struct
+*              - rand io.Reader: source of randomness
+*
+* Returns:     - pk []byte: output public key
+*                (KYBER_PUBLICKEYBYTES bytes)
+*              - sk []byte: output private key
+*                (KYBER_SECRETKEYBYTES bytes)
+*              - err error: non-nil if rand fails
+**************************************************/
+func Crypto_kem_keypair_rand(params *Parameters, rand io.Reader) ([]byte, []byte, error) {
+	coins, err := randombytes_from(rand, 2*KYBER_SYMBYTES)
+	if err != nil {
+		return nil, nil, err
+	}
+	pk, sk := crypto_kem_keypair_derand(params, coins[:KYBER_SYMBYTES], coins[KYBER_SYMBYTES:])
+	return pk, sk, nil
+}
+
+/*************************************************
+* Name:        crypto_kem_keypair_derand
+*
+* Description: Deterministically generates public and private key
+*              for CCA-secure Kyber key encapsulation mechanism
+*
+* Arguments:   - params *Parameters: Kem parameters struct
+*              - d []byte: input seed of the IND-CPA key pair
+*                (of length KYBER_SYMBYTES bytes)
+*              - z []byte: input implicit rejection value
+*                (of length KYBER_SYMBYTES bytes)
+*
+* Returns:     - pk []byte: output public key
+*                (KYBER_PUBLICKEYBYTES bytes)
+*              - sk []byte: output private key
+*                (KYBER_SECRETKEYBYTES bytes)
+**************************************************/
+func crypto_kem_keypair_derand(params *Parameters, d []byte, z []byte) ([]byte, []byte) {
 	pk := make([]byte, params.KYBER_PUBLICKEYBYTES)
 	sk := make([]byte, params.KYBER_SECRETKEYBYTES)
 
-	indcpa_pk, indcpa_sk := Indcpa_keypair_with_recovery(params, seed)
+	indcpa_pk, indcpa_sk := Indcpa_keypair_with_recovery(params, d)
 	copy(pk, indcpa_pk)
 	subtle.ConstantTimeCopy(1, sk[:params.KYBER_INDCPA_SECRETKEYBYTES], indcpa_sk[:])
 
@@ -68,9 +133,7 @@ func Crypto_kem_keypair_with_recovery(params *Parameters, seed []byte) ([]byte,
 	hpk := hash_h(pk, params.KYBER_PUBLICKEYBYTES)
 	copy(sk[pos:pos+KYBER_SYMBYTES], hpk[:])
 
-	/* Value z for pseudo-random output on reject */
-	z := randombytes(KYBER_SYMBYTES)
-	subtle.ConstantTimeCopy(1, sk[(params.KYBER_SECRETKEYBYTES-KYBER_SYMBYTES):], z)
+	subtle.ConstantTimeCopy(1, sk[(params.KYBER_SECRETKEYBYTES-KYBER_SYMBYTES):], z[:KYBER_SYMBYTES])
 	return pk, sk
 }
 
@@ -90,33 +153,92 @@ func Crypto_kem_keypair_with_recovery(params *Parameters, seed []byte) ([]byte,
 *                (KYBER_SSBYTES bytes)
 **************************************************/
 func Crypto_kem_enc(params *Parameters, pk []byte) ([]byte, []byte) {
-	//ct := make([]byte, params.KYBER_CIPHERTEXTBYTES)
+	return crypto_kem_enc_derand(params, pk, randombytes(KYBER_SYMBYTES))
+}
+
+/*************************************************
+* Name:        crypto_kem_enc_rand
+*
+* Description: Generates cipher text and shared
+*              secret for given public key,
+*              drawing all randomness from rand
+*
+* Arguments:   - params *Parameters: Kem parameters struct
+*              - pk []byte: input public key
+*                (of length KYBER_PUBLICKEYBYTES bytes)
+*              - rand io.Reader: source of randomness
+*
+* Returns      - ct []byte: output cipher text
+*                (KYBER_CIPHERTEXTBYTES bytes)
+*              - ss []byte: output shared secret
+*                (KYBER_SSBYTES bytes)
+*              - err error: non-nil on bad public key length
+*                or if rand fails
+**************************************************/
+func Crypto_kem_enc_rand(params *Parameters, pk []byte, rand io.Reader) ([]byte, []byte, error) {
+	if len(pk) != params.KYBER_PUBLICKEYBYTES {
+		return nil, nil, ErrInvalidPublicKeyLength
+	}
+	coins, err := randombytes_from(rand, KYBER_SYMBYTES)
+	if err != nil {
+		return nil, nil, err
+	}
+	ct, ss := crypto_kem_enc_derand(params, pk, coins)
+	return ct, ss, nil
+}
+
+/*************************************************
+* Name:        crypto_kem_enc_derand
+*
+* Description: Generates cipher text and shared
+*              secret for given public key and coins.
+*              In round-3 mode the coins are hashed before use and
+*              the shared secret is kdf(pre-k || H(c)); in ML-KEM mode
+*              the coins are the message m and pre-k is the shared secret
+*
+* Arguments:   - params *Parameters: Kem parameters struct
+*              - pk []byte: input public key
+*                (of length KYBER_PUBLICKEYBYTES bytes)
+*              - coins []byte: input randomness
+*                (of length KYBER_SYMBYTES bytes)
+*
+* Returns      - ct []byte: output cipher text
+*                (KYBER_CIPHERTEXTBYTES bytes)
+*              - ss []byte: output shared secret
+*                (KYBER_SSBYTES bytes)
+**************************************************/
+func crypto_kem_enc_derand(params *Parameters, pk []byte, coins []byte) ([]byte, []byte) {
 	ss := make([]byte, KYBER_SSBYTES)
 
 	buf := make([]byte, 2*KYBER_SYMBYTES)
 
 	var hash_pk [KYBER_SYMBYTES]byte
-	var hash_m [KYBER_SYMBYTES]byte
 	var hash_c [KYBER_SYMBYTES]byte
 
 	/* Will contain key, coins */
 	var kr [2 * KYBER_SYMBYTES]byte
 
-	m := randombytes(KYBER_SYMBYTES)
-
-	/* Don't release system RNG output */
-	hash_m = hash_h(m, KYBER_SYMBYTES)
+	if params.KYBER_MODE == KYBER_MODE_MLKEM {
+		copy(buf[:KYBER_SYMBYTES], coins[:KYBER_SYMBYTES])
+	} else {
+		/* Don't release system RNG output */
+		hash_m := hash_h(coins, KYBER_SYMBYTES)
+		copy(buf[:KYBER_SYMBYTES], hash_m[:])
+	}
 
 	/* Multitarget countermeasure for coins + contributory KEM */
 	hash_pk = hash_h(pk, params.KYBER_PUBLICKEYBYTES)
-
-	copy(buf[:KYBER_SYMBYTES], hash_m[:])
 	copy(buf[KYBER_SYMBYTES:], hash_pk[:])
 
 	kr = hash_g(buf, 2*KYBER_SYMBYTES)
 
 	/* coins are in kr+KYBER_SYMBYTES */
-	ct := Indcpa_enc(params, hash_m[:], pk, kr[KYBER_SYMBYTES:])
+	ct := Indcpa_enc(params, buf[:KYBER_SYMBYTES], pk, kr[KYBER_SYMBYTES:])
+
+	if params.KYBER_MODE == KYBER_MODE_MLKEM {
+		copy(ss, kr[:KYBER_SYMBYTES])
+		return ct, ss
+	}
 
 	/* overwrite coins in kr with H(c) */
 	hash_c = hash_h(ct, params.KYBER_CIPHERTEXTBYTES)
@@ -164,6 +286,13 @@ func Crypto_kem_dec(params *Parameters, ct []byte, sk []byte) []byte {
 
 	fail = subtle.ConstantTimeCompare(ct[:params.KYBER_CIPHERTEXTBYTES], cmp) // 1 means equal 0 means different
 
+	if params.KYBER_MODE == KYBER_MODE_MLKEM {
+		/* Compute rejection key J(z||c) and overwrite it with pre-k on success */
+		rkprf(ss, sk[params.KYBER_SECRETKEYBYTES-KYBER_SYMBYTES:], ct[:params.KYBER_CIPHERTEXTBYTES])
+		subtle.ConstantTimeCopy(fail, ss, kr[:KYBER_SYMBYTES])
+		return ss
+	}
+
 	/* overwrite coins in kr with H(c) */
 	hash_c := hash_h(ct, params.KYBER_CIPHERTEXTBYTES)
 	copy(kr[KYBER_SYMBYTES:], hash_c[:])
@@ -175,3 +304,219 @@ func Crypto_kem_dec(params *Parameters, ct []byte, sk []byte) []byte {
 	kdf(ss, KYBER_SYMBYTES, kr[:], 2*KYBER_SYMBYTES)
 	return ss
 }
+
+/*************************************************
+* Name:        crypto_kem_enc_checked
+*
+* Description: Same as crypto_kem_enc, but validates the length
+*              of the public key instead of panicking on short input
+*
+* Arguments:   - params *Parameters: Kem parameters struct
+*              - pk []byte: input public key
+*                (of length KYBER_PUBLICKEYBYTES bytes)
+*
+* Returns      - ct []byte: output cipher text
+*                (KYBER_CIPHERTEXTBYTES bytes)
+*              - ss []byte: output shared secret
+*                (KYBER_SSBYTES bytes)
+*              - err error: ErrInvalidPublicKeyLength on bad input
+**************************************************/
+func Crypto_kem_enc_checked(params *Parameters, pk []byte) ([]byte, []byte, error) {
+	if len(pk) != params.KYBER_PUBLICKEYBYTES {
+		return nil, nil, ErrInvalidPublicKeyLength
+	}
+	ct, ss := Crypto_kem_enc(params, pk)
+	return ct, ss, nil
+}
+
+/*************************************************
+* Name:        crypto_kem_dec_checked
+*
+* Description: Same as crypto_kem_dec, but validates the lengths
+*              of the cipher text and private key instead of
+*              panicking on short input
+*
+* Arguments:   - params *Parameters: Kem parameters struct
+*              - ct []byte: input cipher text
+*                (of length KYBER_CIPHERTEXTBYTES bytes)
+*              - sk []byte: input private key
+*                (of length KYBER_SECRETKEYBYTES bytes)
+*
+* Returns      - ss []byte: output shared secret
+*                (KYBER_SSBYTES bytes)
+*              - err error: ErrInvalidCiphertextLength or
+*                ErrInvalidSecretKeyLength on bad input
+**************************************************/
+func Crypto_kem_dec_checked(params *Parameters, ct []byte, sk []byte) ([]byte, error) {
+	if len(ct) != params.KYBER_CIPHERTEXTBYTES {
+		return nil, ErrInvalidCiphertextLength
+	}
+	if len(sk) != params.KYBER_SECRETKEYBYTES {
+		return nil, ErrInvalidSecretKeyLength
+	}
+	return Crypto_kem_dec(params, ct, sk), nil
+}
+
+/*************************************************
+* Name:        crypto_kem_check_pk
+*
+* Description: Input check on the public key (encapsulation key
+*              modulus check of FIPS 203): every coefficient must
+*              already be reduced modulo q, i.e. decoding and
+*              re-encoding the polynomial vector gives the input back
+*
+* Arguments:   - params *Parameters: Kem parameters struct
+*              - pk []byte: input public key
+*                (of length KYBER_PUBLICKEYBYTES bytes)
+*
+* Returns      - err error: ErrInvalidPublicKeyLength or
+*                ErrInvalidPublicKeyEncoding on bad input
+**************************************************/
+func Crypto_kem_check_pk(params *Parameters, pk []byte) error {
+	if len(pk) != params.KYBER_PUBLICKEYBYTES {
+		return ErrInvalidPublicKeyLength
+	}
+
+	pkpv := polyvec_frombytes(params, pk)
+	polyvec_reduce(params, pkpv)
+	if !bytes.Equal(polyvec_tobytes(params, pkpv), pk[:params.KYBER_POLYVECBYTES]) {
+		return ErrInvalidPublicKeyEncoding
+	}
+	return nil
+}
+
+/*************************************************
+* Name:        crypto_kem_check_sk
+*
+* Description: Input check on the private key (decapsulation key
+*              hash check of FIPS 203): the H(pk) stored in sk must
+*              match the public key embedded in sk
+*
+* Arguments:   - params *Parameters: Kem parameters struct
+*              - sk []byte: input private key
+*                (of length KYBER_SECRETKEYBYTES bytes)
+*
+* Returns      - err error: ErrInvalidSecretKeyLength or
+*                ErrInvalidSecretKeyHash on bad input
+**************************************************/
+func Crypto_kem_check_sk(params *Parameters, sk []byte) error {
+	if len(sk) != params.KYBER_SECRETKEYBYTES {
+		return ErrInvalidSecretKeyLength
+	}
+
+	pos := params.KYBER_INDCPA_SECRETKEYBYTES + params.KYBER_INDCPA_PUBLICKEYBYTES
+	hpk := hash_h(sk[params.KYBER_INDCPA_SECRETKEYBYTES:pos], params.KYBER_PUBLICKEYBYTES)
+	if subtle.ConstantTimeCompare(hpk[:], sk[pos:pos+KYBER_SYMBYTES]) != 1 {
+		return ErrInvalidSecretKeyHash
+	}
+	return nil
+}
+
+/*************************************************
+* Name:        crypto_kem_enc_strict
+*
+* Description: Same as crypto_kem_enc_checked, but additionally
+*              rejects public keys failing crypto_kem_check_pk
+*
+* Arguments:   - params *Parameters: Kem parameters struct
+*              - pk []byte: input public key
+*                (of length KYBER_PUBLICKEYBYTES bytes)
+*
+* Returns      - ct []byte: output cipher text
+*                (KYBER_CIPHERTEXTBYTES bytes)
+*              - ss []byte: output shared secret
+*                (KYBER_SSBYTES bytes)
+*              - err error: non-nil on invalid public key
+**************************************************/
+func Crypto_kem_enc_strict(params *Parameters, pk []byte) ([]byte, []byte, error) {
+	if err := Crypto_kem_check_pk(params, pk); err != nil {
+		return nil, nil, err
+	}
+	ct, ss := Crypto_kem_enc(params, pk)
+	return ct, ss, nil
+}
+
+/*************************************************
+* Name:        crypto_kem_dec_strict
+*
+* Description: Same as crypto_kem_dec_checked, but additionally
+*              rejects private keys failing crypto_kem_check_sk
+*
+* Arguments:   - params *Parameters: Kem parameters struct
+*              - ct []byte: input cipher text
+*                (of length KYBER_CIPHERTEXTBYTES bytes)
+*              - sk []byte: input private key
+*                (of length KYBER_SECRETKEYBYTES bytes)
+*
+* Returns      - ss []byte: output shared secret
+*                (KYBER_SSBYTES bytes)
+*              - err error: non-nil on invalid input
+**************************************************/
+func Crypto_kem_dec_strict(params *Parameters, ct []byte, sk []byte) ([]byte, error) {
+	if len(ct) != params.KYBER_CIPHERTEXTBYTES {
+		return nil, ErrInvalidCiphertextLength
+	}
+	if err := Crypto_kem_check_sk(params, sk); err != nil {
+		return nil, err
+	}
+	return Crypto_kem_dec(params, ct, sk), nil
+}
+
+/*************************************************
+* Name:        KeypairDerand
+*
+* Description: Deterministically generates public and private key
+*              from caller-provided randomness, for KAT generation
+*              and test vectors (ML-KEM.KeyGen_internal of FIPS 203)
+*
+* Arguments:   - params *Parameters: Kem parameters struct
+*              - d []byte: input seed of the IND-CPA key pair
+*                (of length KYBER_SYMBYTES bytes)
+*              - z []byte: input implicit rejection value
+*                (of length KYBER_SYMBYTES bytes)
+*
+* Returns:     - pk []byte: output public key
+*                (KYBER_PUBLICKEYBYTES bytes)
+*              - sk []byte: output private key
+*                (KYBER_SECRETKEYBYTES bytes)
+*              - err error: ErrInvalidSeedLength on bad input
+**************************************************/
+func KeypairDerand(params *Parameters, d []byte, z []byte) ([]byte, []byte, error) {
+	if len(d) != KYBER_SYMBYTES || len(z) != KYBER_SYMBYTES {
+		return nil, nil, ErrInvalidSeedLength
+	}
+	pk, sk := crypto_kem_keypair_derand(params, d, z)
+	return pk, sk, nil
+}
+
+/*************************************************
+* Name:        EncapsulateDerand
+*
+* Description: Deterministically generates cipher text and shared
+*              secret from caller-provided randomness, for KAT
+*              generation and test vectors. In ML-KEM mode m is the
+*              message of ML-KEM.Encaps_internal of FIPS 203, in
+*              round-3 mode it is the RNG output hashed by crypto_kem_enc
+*
+* Arguments:   - params *Parameters: Kem parameters struct
+*              - pk []byte: input public key
+*                (of length KYBER_PUBLICKEYBYTES bytes)
+*              - m []byte: input randomness
+*                (of length KYBER_SYMBYTES bytes)
+*
+* Returns      - ct []byte: output cipher text
+*                (KYBER_CIPHERTEXTBYTES bytes)
+*              - ss []byte: output shared secret
+*                (KYBER_SSBYTES bytes)
+*              - err error: non-nil on bad input lengths
+**************************************************/
+func EncapsulateDerand(params *Parameters, pk []byte, m []byte) ([]byte, []byte, error) {
+	if len(pk) != params.KYBER_PUBLICKEYBYTES {
+		return nil, nil, ErrInvalidPublicKeyLength
+	}
+	if len(m) != KYBER_SYMBYTES {
+		return nil, nil, ErrInvalidMessageLength
+	}
+	ct, ss := crypto_kem_enc_derand(params, pk, m)
+	return ct, ss, nil
+}
@@ -4,11 +4,15 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"testing"
 	"time"
+
+	"golang.org/x/crypto/sha3"
 )
 
 const TESTN = 1000
@@ -22,45 +26,6 @@ type ERRCOUNT struct {
 	dec_fail int
 }
 
-// Just for testing kem_enc result with C version
-func crypto_kem_enc_testwithseed(params *Parameters, pk []byte, m []byte) ([]byte, []byte) {
-	//ct := make([]byte, params.KYBER_CIPHERTEXTBYTES)
-	ss := make([]byte, KYBER_SSBYTES)
-
-	buf := make([]byte, 2*KYBER_SYMBYTES)
-	//m := make([]byte, KYBER_SYMBYTES)
-	var hash_pk [KYBER_SYMBYTES]byte
-	var hash_m [KYBER_SYMBYTES]byte
-	var hash_c [KYBER_SYMBYTES]byte
-
-	/* Will contain key, coins */
-	var kr [2 * KYBER_SYMBYTES]byte
-
-	//randombytes(m, KYBER_SYMBYTES)
-
-	/* Don't release system RNG output */
-	hash_m = hash_h(m, KYBER_SYMBYTES)
-
-	/* Multitarget countermeasure for coins + contributory KEM */
-	hash_pk = hash_h(pk, params.KYBER_PUBLICKEYBYTES)
-
-	copy(buf[:KYBER_SYMBYTES], hash_m[:])
-	copy(buf[KYBER_SYMBYTES:], hash_pk[:])
-
-	kr = hash_g(buf, 2*KYBER_SYMBYTES)
-
-	/* coins are in kr+KYBER_SYMBYTES */
-	ct := Indcpa_enc(params, hash_m[:], pk, kr[KYBER_SYMBYTES:])
-
-	/* overwrite coins in kr with H(c) */
-	hash_c = hash_h(ct, params.KYBER_CIPHERTEXTBYTES)
-	copy(kr[KYBER_SYMBYTES:], hash_c[:])
-
-	/* hash concatenation of pre-k and H(c) to k */
-	kdf(ss, KYBER_SSBYTES, kr[:], 2*KYBER_SYMBYTES)
-	return ct, ss
-}
-
 func convertInputString(line string) []byte {
 	buf := strings.Split(line, " ")
 	buf2 := strings.TrimSpace(buf[2])
@@ -110,7 +75,10 @@ func compare_with_C_txt(t *testing.T, params *Parameters) {
 		line, _ = reader.ReadString('\n')
 		filect := convertInputString(line)
 
-		ct, ss := crypto_kem_enc_testwithseed(params, pk, m)
+		ct, ss, err := EncapsulateDerand(params, pk, m)
+		if err != nil {
+			t.Fatal(err)
+		}
 		if !bytes.Equal(filess, ss) {
 			err_count.ss++
 		}
@@ -220,6 +188,9 @@ func Test_Kem(t *testing.T) { //test kem correctness
 	params_1024 := NewParameters(4)
 	kem_correctness(params_1024)
 
+	kem_correctness(NewMLKEMParameters(2))
+	kem_correctness(NewMLKEMParameters(3))
+	kem_correctness(NewMLKEMParameters(4))
 }
 
 func Test_Speed(t *testing.T) {
@@ -236,3 +207,201 @@ func Test_Speed(t *testing.T) {
 	kem_speed(params_1024)
 	fmt.Println()
 }
+
+func Test_Kem_checked(t *testing.T) {
+	for _, params := range []*Parameters{NewParameters(2), NewParameters(3), NewParameters(4), NewMLKEMParameters(3)} {
+		pk, sk := Crypto_kem_keypair(params)
+
+		if _, _, err := Crypto_kem_enc_checked(params, pk[:len(pk)-1]); !errors.Is(err, ErrInvalidPublicKeyLength) {
+			t.Errorf("%s: short public key: got %v", params.KYBER_NAME, err)
+		}
+
+		ct, ss, err := Crypto_kem_enc_checked(params, pk)
+		if err != nil {
+			t.Fatalf("%s: %v", params.KYBER_NAME, err)
+		}
+
+		if _, err := Crypto_kem_dec_checked(params, ct[:10], sk); !errors.Is(err, ErrInvalidCiphertextLength) {
+			t.Errorf("%s: short ciphertext: got %v", params.KYBER_NAME, err)
+		}
+		if _, err := Crypto_kem_dec_checked(params, ct, sk[:params.KYBER_INDCPA_SECRETKEYBYTES]); !errors.Is(err, ErrInvalidSecretKeyLength) {
+			t.Errorf("%s: short secret key: got %v", params.KYBER_NAME, err)
+		}
+
+		ss2, err := Crypto_kem_dec_checked(params, ct, sk)
+		if err != nil {
+			t.Fatalf("%s: %v", params.KYBER_NAME, err)
+		}
+		if !bytes.Equal(ss, ss2) {
+			t.Errorf("%s: shared secrets differ", params.KYBER_NAME)
+		}
+
+		if _, err := Indcpa_enc_checked(params, make([]byte, KYBER_INDCPA_MSGBYTES), pk[:3], make([]byte, KYBER_SYMBYTES)); !errors.Is(err, ErrInvalidPublicKeyLength) {
+			t.Errorf("%s: short indcpa public key: got %v", params.KYBER_NAME, err)
+		}
+		if _, err := Indcpa_dec_checked(params, ct, nil); !errors.Is(err, ErrInvalidSecretKeyLength) {
+			t.Errorf("%s: empty indcpa secret key: got %v", params.KYBER_NAME, err)
+		}
+	}
+}
+
+func Test_Kem_strict(t *testing.T) {
+	for _, params := range []*Parameters{NewParameters(2), NewMLKEMParameters(3), NewMLKEMParameters(4)} {
+		pk, sk := Crypto_kem_keypair(params)
+
+		if err := Crypto_kem_check_pk(params, pk); err != nil {
+			t.Errorf("%s: valid public key rejected: %v", params.KYBER_NAME, err)
+		}
+		if err := Crypto_kem_check_sk(params, sk); err != nil {
+			t.Errorf("%s: valid secret key rejected: %v", params.KYBER_NAME, err)
+		}
+
+		// Set the first coefficient to q, which decodes but is not reduced
+		badpk := bytes.Clone(pk)
+		badpk[0] = KYBER_Q & 0xFF
+		badpk[1] = (badpk[1] & 0xF0) | (KYBER_Q >> 8)
+		if _, _, err := Crypto_kem_enc_strict(params, badpk); !errors.Is(err, ErrInvalidPublicKeyEncoding) {
+			t.Errorf("%s: unreduced public key: got %v", params.KYBER_NAME, err)
+		}
+
+		ct, ss, err := Crypto_kem_enc_strict(params, pk)
+		if err != nil {
+			t.Fatalf("%s: %v", params.KYBER_NAME, err)
+		}
+
+		badsk := bytes.Clone(sk)
+		badsk[params.KYBER_INDCPA_SECRETKEYBYTES] ^= 1
+		if _, err := Crypto_kem_dec_strict(params, ct, badsk); !errors.Is(err, ErrInvalidSecretKeyHash) {
+			t.Errorf("%s: inconsistent secret key: got %v", params.KYBER_NAME, err)
+		}
+
+		ss2, err := Crypto_kem_dec_strict(params, ct, sk)
+		if err != nil {
+			t.Fatalf("%s: %v", params.KYBER_NAME, err)
+		}
+		if !bytes.Equal(ss, ss2) {
+			t.Errorf("%s: shared secrets differ", params.KYBER_NAME)
+		}
+	}
+}
+
+// Deterministic randomness for tests
+func newTestRand(seed string) io.Reader {
+	h := sha3.NewShake128()
+	h.Write([]byte(seed))
+	return h
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("entropy source unavailable") }
+
+func Test_Kem_rand(t *testing.T) {
+	for _, params := range []*Parameters{NewParameters(2), NewMLKEMParameters(3)} {
+		pk, sk, err := Crypto_kem_keypair_rand(params, newTestRand("keypair"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pk2, sk2, _ := Crypto_kem_keypair_rand(params, newTestRand("keypair"))
+		if !bytes.Equal(pk, pk2) || !bytes.Equal(sk, sk2) {
+			t.Errorf("%s: keypair not reproducible from the same reader", params.KYBER_NAME)
+		}
+
+		ct, ss, err := Crypto_kem_enc_rand(params, pk, newTestRand("enc"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ct2, ss2, _ := Crypto_kem_enc_rand(params, pk, newTestRand("enc"))
+		if !bytes.Equal(ct, ct2) || !bytes.Equal(ss, ss2) {
+			t.Errorf("%s: encapsulation not reproducible from the same reader", params.KYBER_NAME)
+		}
+		if !bytes.Equal(Crypto_kem_dec(params, ct, sk), ss) {
+			t.Errorf("%s: shared secrets differ", params.KYBER_NAME)
+		}
+
+		if _, _, err := Crypto_kem_keypair_rand(params, failingReader{}); err == nil {
+			t.Errorf("%s: keypair ignored RNG failure", params.KYBER_NAME)
+		}
+		if _, _, err := Crypto_kem_enc_rand(params, pk, failingReader{}); err == nil {
+			t.Errorf("%s: encapsulation ignored RNG failure", params.KYBER_NAME)
+		}
+		if _, _, err := Indcpa_keypair_rand(params, failingReader{}); err == nil {
+			t.Errorf("%s: indcpa keypair ignored RNG failure", params.KYBER_NAME)
+		}
+		if _, err := GenerateKeyRand(params, failingReader{}); err == nil {
+			t.Errorf("%s: GenerateKeyRand ignored RNG failure", params.KYBER_NAME)
+		}
+	}
+}
+
+func Test_Kem_derand(t *testing.T) {
+	for _, params := range []*Parameters{NewParameters(3), NewMLKEMParameters(3)} {
+		d := bytes.Repeat([]byte{1}, KYBER_SYMBYTES)
+		z := bytes.Repeat([]byte{2}, KYBER_SYMBYTES)
+		m := bytes.Repeat([]byte{3}, KYBER_SYMBYTES)
+
+		pk, sk, err := KeypairDerand(params, d, z)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(sk[len(sk)-KYBER_SYMBYTES:], z) {
+			t.Errorf("%s: z not stored in secret key", params.KYBER_NAME)
+		}
+		ct, ss, err := EncapsulateDerand(params, pk, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ct2, ss2, _ := EncapsulateDerand(params, pk, m)
+		if !bytes.Equal(ct, ct2) || !bytes.Equal(ss, ss2) {
+			t.Errorf("%s: EncapsulateDerand not deterministic", params.KYBER_NAME)
+		}
+		if !bytes.Equal(Crypto_kem_dec(params, ct, sk), ss) {
+			t.Errorf("%s: shared secrets differ", params.KYBER_NAME)
+		}
+
+		if _, _, err := KeypairDerand(params, d, z[:5]); !errors.Is(err, ErrInvalidSeedLength) {
+			t.Errorf("%s: short z: got %v", params.KYBER_NAME, err)
+		}
+		if _, _, err := EncapsulateDerand(params, pk, m[:5]); !errors.Is(err, ErrInvalidMessageLength) {
+			t.Errorf("%s: short m: got %v", params.KYBER_NAME, err)
+		}
+	}
+}
+
+func Test_Kem_recovery(t *testing.T) {
+	for _, params := range []*Parameters{NewParameters(4), NewMLKEMParameters(2)} {
+		seed := randombytes(KYBER_SEEDBYTES)
+
+		pk, sk := Crypto_kem_keypair_with_recovery(params, seed)
+		pk2, sk2 := Crypto_kem_keypair_with_recovery(params, seed)
+		if !bytes.Equal(pk, pk2) || !bytes.Equal(sk, sk2) {
+			t.Errorf("%s: recovery from d||z is not byte-identical", params.KYBER_NAME)
+		}
+
+		pk3, sk3, err := Crypto_kem_keypair_from_seed(params, seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(pk, pk3) || !bytes.Equal(sk, sk3) {
+			t.Errorf("%s: Crypto_kem_keypair_from_seed differs from recovery", params.KYBER_NAME)
+		}
+
+		priv, err := NewPrivateKeyFromSeed(params, seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(priv.Bytes(), sk) {
+			t.Errorf("%s: NewPrivateKeyFromSeed differs from recovery", params.KYBER_NAME)
+		}
+
+		if _, _, err := Crypto_kem_keypair_from_seed(params, seed[:KYBER_SYMBYTES]); !errors.Is(err, ErrInvalidSeedLength) {
+			t.Errorf("%s: short seed: got %v", params.KYBER_NAME, err)
+		}
+
+		// Legacy 32-byte seeds still recover everything but z
+		_, sk4 := Crypto_kem_keypair_with_recovery(params, seed[:KYBER_SYMBYTES])
+		if !bytes.Equal(sk4[:len(sk4)-KYBER_SYMBYTES], sk[:len(sk)-KYBER_SYMBYTES]) {
+			t.Errorf("%s: legacy recovery differs", params.KYBER_NAME)
+		}
+	}
+}
@@ -1,5 +1,10 @@
 package kyber
 
+import (
+	"crypto/rand"
+	"io"
+)
+
 const (
 	CRYPTO_BYTES = KYBER_SSBYTES
 	KEX_SSBYTES  = KYBER_SSBYTES
@@ -19,8 +24,17 @@ type KexParameters struct {
 }
 
 func NewKexParameters(k int) *KexParameters {
+	return newKexParameters(NewParameters(k))
+}
+
+// NewMLKEMKexParameters returns the Kex parameters over ML-KEM with k in {2,3,4}
+func NewMLKEMKexParameters(k int) *KexParameters {
+	return newKexParameters(NewMLKEMParameters(k))
+}
+
+func newKexParameters(params *Parameters) *KexParameters {
 	var kexpp KexParameters
-	kexpp.KemParams = NewParameters(k)
+	kexpp.KemParams = params
 	kexpp.CRYPTO_SECRETKEYBYTES = kexpp.KemParams.KYBER_SECRETKEYBYTES
 	kexpp.CRYPTO_PUBLICKEYBYTES = kexpp.KemParams.KYBER_PUBLICKEYBYTES
 	kexpp.CRYPTO_CIPHERTEXTBYTES = kexpp.KemParams.KYBER_CIPHERTEXTBYTES
@@ -34,23 +48,70 @@ func NewKexParameters(k int) *KexParameters {
 	return &kexpp
 }
 
+// Kex_uake_initA starts the UAKE and returns send, tk, sk. As in earlier
+// releases only the first KYBER_PUBLICKEYBYTES bytes of pkb are read.
+// Unlike earlier releases, which panicked, a short pkb returns nil, nil,
+// nil. It panics only if crypto/rand fails; new code should use
+// Kex_uake_initA_rand, which returns all errors.
 func Kex_uake_initA(kexpp *KexParameters, pkb []byte) ([]byte, []byte, []byte) {
+	pkb, ok := kex_trim(pkb, kexpp.CRYPTO_PUBLICKEYBYTES)
+	if !ok {
+		return nil, nil, nil
+	}
+	send, tk, sk, err := Kex_uake_initA_rand(kexpp, pkb, rand.Reader)
+	if err != nil {
+		panic(err) // the lengths are checked, only rand.Reader can fail
+	}
+	return send, tk, sk
+}
+
+func Kex_uake_initA_rand(kexpp *KexParameters, pkb []byte, rand io.Reader) ([]byte, []byte, []byte, error) {
 	send := make([]byte, kexpp.KEX_UAKE_SENDABYTES)
-	pk, sk := Crypto_kem_keypair(kexpp.KemParams)
+	pk, sk, err := Crypto_kem_keypair_rand(kexpp.KemParams, rand)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 	copy(send, pk)
-	ct, tk := Crypto_kem_enc(kexpp.KemParams, pkb)
+	ct, tk, err := Crypto_kem_enc_rand(kexpp.KemParams, pkb, rand)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 	copy(send[kexpp.CRYPTO_PUBLICKEYBYTES:], ct)
-	return send, tk, sk
+	return send, tk, sk, nil
 }
 
+// Kex_uake_sharedB answers recv and returns send, k. As in earlier
+// releases bytes of recv beyond KEX_UAKE_SENDABYTES are ignored. Unlike
+// earlier releases, which panicked, a short recv returns nil, nil: callers
+// must check for a nil k. It panics only if crypto/rand fails; new code
+// should use Kex_uake_sharedB_rand, which reports malformed messages as
+// ErrInvalidKexMessageLength.
 func Kex_uake_sharedB(kexpp *KexParameters, recv []byte, skb []byte) ([]byte, []byte) {
+	recv, ok := kex_trim(recv, kexpp.KEX_UAKE_SENDABYTES)
+	if !ok {
+		return nil, nil
+	}
+	send, k, err := Kex_uake_sharedB_rand(kexpp, recv, skb, rand.Reader)
+	if err != nil {
+		panic(err) // the lengths are checked, only rand.Reader can fail
+	}
+	return send, k
+}
+
+func Kex_uake_sharedB_rand(kexpp *KexParameters, recv []byte, skb []byte, rand io.Reader) ([]byte, []byte, error) {
+	if len(recv) != kexpp.KEX_UAKE_SENDABYTES {
+		return nil, nil, ErrInvalidKexMessageLength
+	}
 	k := make([]byte, KYBER_SSBYTES)
 	buf := make([]byte, 2*CRYPTO_BYTES)
-	send, ss := Crypto_kem_enc(kexpp.KemParams, recv)
+	send, ss, err := Crypto_kem_enc_rand(kexpp.KemParams, recv[:kexpp.CRYPTO_PUBLICKEYBYTES], rand)
+	if err != nil {
+		return nil, nil, err
+	}
 	copy(buf, ss)
 	copy(buf[CRYPTO_BYTES:], Crypto_kem_dec(kexpp.KemParams, recv[kexpp.CRYPTO_PUBLICKEYBYTES:], skb))
 	kdf(k, len(k), buf, 2*CRYPTO_BYTES)
-	return send, k
+	return send, k, nil
 }
 
 func Kex_uake_sharedA(kexpp *KexParameters, recv []byte, tk []byte, sk []byte) []byte {
@@ -64,33 +125,101 @@ func Kex_uake_sharedA(kexpp *KexParameters, recv []byte, tk []byte, sk []byte) [
 	return k
 }
 
+// Kex_ake_initA starts the AKE and returns send, tk, sk, reading pkb as
+// Kex_uake_initA does: a short pkb returns nil, nil, nil. It panics only
+// if crypto/rand fails; new code should use Kex_ake_initA_rand.
 func Kex_ake_initA(kexpp *KexParameters, pkb []byte) ([]byte, []byte, []byte) {
-	send := make([]byte, kexpp.KEX_UAKE_SENDABYTES)
-	pk, sk := Crypto_kem_keypair(kexpp.KemParams)
+	pkb, ok := kex_trim(pkb, kexpp.CRYPTO_PUBLICKEYBYTES)
+	if !ok {
+		return nil, nil, nil
+	}
+	send, tk, sk, err := Kex_ake_initA_rand(kexpp, pkb, rand.Reader)
+	if err != nil {
+		panic(err) // the lengths are checked, only rand.Reader can fail
+	}
+	return send, tk, sk
+}
+
+func Kex_ake_initA_rand(kexpp *KexParameters, pkb []byte, rand io.Reader) ([]byte, []byte, []byte, error) {
+	send := make([]byte, kexpp.KEX_AKE_SENDABYTES)
+	pk, sk, err := Crypto_kem_keypair_rand(kexpp.KemParams, rand)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 
 	copy(send, pk)
-	ct, tk := Crypto_kem_enc(kexpp.KemParams, pkb)
+	ct, tk, err := Crypto_kem_enc_rand(kexpp.KemParams, pkb, rand)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 	copy(send[kexpp.CRYPTO_PUBLICKEYBYTES:], ct)
-	return send, tk, sk
+	return send, tk, sk, nil
 }
 
+// Kex_ake_sharedB answers recv and returns send, k. As in earlier
+// releases bytes of recv beyond KEX_AKE_SENDABYTES and of pka beyond
+// KYBER_PUBLICKEYBYTES are ignored. Unlike earlier releases, which
+// panicked, a short recv or pka returns nil, nil: callers must check for
+// a nil k. It panics only if crypto/rand fails; new code should use
+// Kex_ake_sharedB_rand, which reports malformed messages as
+// ErrInvalidKexMessageLength.
 func Kex_ake_sharedB(kexpp *KexParameters, recv []byte, skb []byte, pka []byte) ([]byte, []byte) {
+	recv, ok := kex_trim(recv, kexpp.KEX_AKE_SENDABYTES)
+	if !ok {
+		return nil, nil
+	}
+	pka, ok = kex_trim(pka, kexpp.CRYPTO_PUBLICKEYBYTES)
+	if !ok {
+		return nil, nil
+	}
+	send, k, err := Kex_ake_sharedB_rand(kexpp, recv, skb, pka, rand.Reader)
+	if err != nil {
+		panic(err) // the lengths are checked, only rand.Reader can fail
+	}
+	return send, k
+}
+
+func Kex_ake_sharedB_rand(kexpp *KexParameters, recv []byte, skb []byte, pka []byte, rand io.Reader) ([]byte, []byte, error) {
+	send, buf, err := kex_ake_sharedB_ss(kexpp, recv, skb, pka, rand)
+	if err != nil {
+		return nil, nil, err
+	}
 	k := make([]byte, KYBER_SSBYTES)
+	kdf(k, len(k), buf, 3*CRYPTO_BYTES)
+	return send, k, nil
+}
+
+// kex_ake_sharedB_ss returns the answer of B and ss1||ss2||ss3
+func kex_ake_sharedB_ss(kexpp *KexParameters, recv []byte, skb []byte, pka []byte, rand io.Reader) ([]byte, []byte, error) {
+	if len(recv) != kexpp.KEX_AKE_SENDABYTES {
+		return nil, nil, ErrInvalidKexMessageLength
+	}
 	send := make([]byte, kexpp.KEX_AKE_SENDBBYTES)
 	buf := make([]byte, 3*CRYPTO_BYTES)
-	ct, ss := Crypto_kem_enc(kexpp.KemParams, recv)
+	ct, ss, err := Crypto_kem_enc_rand(kexpp.KemParams, recv[:kexpp.CRYPTO_PUBLICKEYBYTES], rand)
+	if err != nil {
+		return nil, nil, err
+	}
 	copy(send, ct)
 	copy(buf, ss)
-	ct2, ss2 := Crypto_kem_enc(kexpp.KemParams, pka)
+	ct2, ss2, err := Crypto_kem_enc_rand(kexpp.KemParams, pka, rand)
+	if err != nil {
+		return nil, nil, err
+	}
 	copy(send[kexpp.CRYPTO_CIPHERTEXTBYTES:], ct2)
 	copy(buf[CRYPTO_BYTES:], ss2)
 	copy(buf[2*CRYPTO_BYTES:], Crypto_kem_dec(kexpp.KemParams, recv[kexpp.CRYPTO_PUBLICKEYBYTES:], skb))
-	kdf(k, len(k), buf, 3*CRYPTO_BYTES)
-	return send, k
+	return send, buf, nil
 }
 
 func Kex_ake_sharedA(kexpp *KexParameters, recv []byte, tk []byte, sk []byte, ska []byte) []byte {
 	k := make([]byte, KYBER_SSBYTES)
+	kdf(k, len(k), kex_ake_sharedA_ss(kexpp, recv, tk, sk, ska), 3*CRYPTO_BYTES)
+	return k
+}
+
+// kex_ake_sharedA_ss returns ss1||ss2||ss3 in the order of kex_ake_sharedB_ss
+func kex_ake_sharedA_ss(kexpp *KexParameters, recv []byte, tk []byte, sk []byte, ska []byte) []byte {
 	buf := make([]byte, 3*CRYPTO_BYTES)
 
 	copy(buf, Crypto_kem_dec(kexpp.KemParams, recv, sk))
@@ -98,6 +227,14 @@ func Kex_ake_sharedA(kexpp *KexParameters, recv []byte, tk []byte, sk []byte, sk
 	for i := 0; i < CRYPTO_BYTES; i++ {
 		buf[i+2*CRYPTO_BYTES] = tk[i]
 	}
-	kdf(k, len(k), buf, 3*CRYPTO_BYTES)
-	return k
+	return buf
+}
+
+// kex_trim returns the first n bytes of b, the part the legacy wrappers
+// read, and false if b is shorter
+func kex_trim(b []byte, n int) ([]byte, bool) {
+	if len(b) < n {
+		return nil, false
+	}
+	return b[:n], true
 }
@@ -0,0 +1,230 @@
+package kex
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	clientKeyLabel = "kyber-go kex client to server"
+	serverKeyLabel = "kyber-go kex server to client"
+
+	// closeTimeout bounds the wait for the peer to take the close record
+	closeTimeout = 5 * time.Second
+)
+
+// halfConn is one direction of the record layer
+type halfConn struct {
+	sync.Mutex
+	aead cipher.AEAD
+	seq  uint64
+	buf  []byte // decrypted, not yet returned
+	raw  []byte // record read in part before a timeout
+	err  error  // sticky error, never a timeout
+}
+
+func (hc *halfConn) nonce() ([]byte, error) {
+	if hc.seq == 1<<64-1 {
+		return nil, errors.New("kex: record sequence number exhausted")
+	}
+	n := make([]byte, chacha20poly1305.NonceSize)
+	binary.BigEndian.PutUint64(n[4:], hc.seq)
+	hc.seq++
+	return n, nil
+}
+
+func newAEAD(ck []byte, label string) (cipher.AEAD, error) {
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ck, nil, []byte(label)), key); err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.New(key)
+}
+
+func (c *Conn) setKeys(ck []byte) error {
+	defer func() {
+		for i := range ck {
+			ck[i] = 0
+		}
+	}()
+	clientAEAD, err := newAEAD(ck, clientKeyLabel)
+	if err != nil {
+		return err
+	}
+	serverAEAD, err := newAEAD(ck, serverKeyLabel)
+	if err != nil {
+		return err
+	}
+	if c.isClient {
+		c.out.aead, c.in.aead = clientAEAD, serverAEAD
+	} else {
+		c.out.aead, c.in.aead = serverAEAD, clientAEAD
+	}
+	return nil
+}
+
+// writeRecord seals p in one record of type typ; c.out must be locked
+func (c *Conn) writeRecord(typ byte, p []byte) error {
+	nonce, err := c.out.nonce()
+	if err != nil {
+		return err
+	}
+	f := &frame{typ: typ, paramID: c.paramID()}
+	f.body = make([]byte, len(p)+c.out.aead.Overhead())
+	f.body = c.out.aead.Seal(f.body[:0], nonce, p, f.header())
+	return writeFrame(c.conn, f)
+}
+
+// Write encrypts p in records of at most 16 KiB
+func (c *Conn) Write(p []byte) (int, error) {
+	if err := c.Handshake(); err != nil {
+		return 0, err
+	}
+	c.out.Lock()
+	defer c.out.Unlock()
+	if c.out.err != nil {
+		return 0, c.out.err
+	}
+	n := 0
+	for len(p) > 0 {
+		m := len(p)
+		if m > maxPlaintext {
+			m = maxPlaintext
+		}
+		if err := c.writeRecord(typeData, p[:m]); err != nil {
+			c.out.err = err
+			return n, err
+		}
+		n += m
+		p = p[m:]
+	}
+	return n, nil
+}
+
+// Read decrypts the next records into p. It returns io.EOF once the
+// peer closed the connection with Close, and io.ErrUnexpectedEOF if the
+// connection ended without it. Other errors end the connection, except an
+// expired read deadline, after which Read can be called again
+func (c *Conn) Read(p []byte) (int, error) {
+	if err := c.Handshake(); err != nil {
+		return 0, err
+	}
+	c.in.Lock()
+	defer c.in.Unlock()
+	for len(c.in.buf) == 0 {
+		if c.in.err != nil {
+			return 0, c.in.err
+		}
+		// a timeout keeps the partial record, so the read can be retried
+		// with a later deadline
+		err := c.readRecord()
+		if isTimeout(err) {
+			return 0, err
+		}
+		c.in.err = err
+	}
+	n := copy(p, c.in.buf)
+	c.in.buf = c.in.buf[n:]
+	return n, nil
+}
+
+// isTimeout reports whether err is a read deadline expiring
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.Is(err, os.ErrDeadlineExceeded) || (errors.As(err, &ne) && ne.Timeout())
+}
+
+// readRaw reads c.in.raw up to n bytes, keeping what was read on error
+func (c *Conn) readRaw(n int) error {
+	for len(c.in.raw) < n {
+		if cap(c.in.raw) < n {
+			c.in.raw = append(make([]byte, 0, n), c.in.raw...)
+		}
+		m, err := c.conn.Read(c.in.raw[len(c.in.raw):n])
+		c.in.raw = c.in.raw[:len(c.in.raw)+m]
+		if err == io.EOF && len(c.in.raw) < n {
+			return io.ErrUnexpectedEOF
+		}
+		if err != nil && len(c.in.raw) < n {
+			return err
+		}
+	}
+	return nil
+}
+
+// readRecord reads and opens one record; c.in must be locked
+func (c *Conn) readRecord() error {
+	if err := c.readRaw(headerSize); err != nil {
+		return err
+	}
+	if err := c.readRaw(headerSize + int(binary.BigEndian.Uint16(c.in.raw[3:headerSize]))); err != nil {
+		return err
+	}
+	f, header, err := readFrame(bytes.NewReader(c.in.raw))
+	c.in.raw = c.in.raw[:0]
+	if err != nil {
+		return err
+	}
+	if f.paramID != c.paramID() || (f.typ != typeData && f.typ != typeClose) {
+		return ErrUnexpectedMessage
+	}
+	nonce, err := c.in.nonce()
+	if err != nil {
+		return err
+	}
+	pt, err := c.in.aead.Open(f.body[:0], nonce, f.body, header)
+	if err != nil {
+		return ErrDecryption
+	}
+	if f.typ == typeClose {
+		if len(pt) != 0 {
+			return ErrUnexpectedMessage
+		}
+		return io.EOF
+	}
+	c.in.buf = pt
+	return nil
+}
+
+// Close sends a close record if the handshake completed, then closes the
+// underlying connection
+func (c *Conn) Close() error {
+	var alertErr error
+	if c.handshakeDone.Load() {
+		c.out.Lock()
+		if c.out.err == nil {
+			c.conn.SetWriteDeadline(time.Now().Add(closeTimeout))
+			alertErr = c.writeRecord(typeClose, nil)
+			c.out.err = net.ErrClosed
+		}
+		c.out.Unlock()
+	}
+	if err := c.conn.Close(); err != nil {
+		return err
+	}
+	return alertErr
+}
+
+func (c *Conn) LocalAddr() net.Addr                { return c.conn.LocalAddr() }
+func (c *Conn) RemoteAddr() net.Addr               { return c.conn.RemoteAddr() }
+func (c *Conn) SetDeadline(t time.Time) error      { return c.conn.SetDeadline(t) }
+func (c *Conn) SetReadDeadline(t time.Time) error  { return c.conn.SetReadDeadline(t) }
+func (c *Conn) SetWriteDeadline(t time.Time) error { return c.conn.SetWriteDeadline(t) }
+
+// NetConn returns the underlying connection
+func (c *Conn) NetConn() net.Conn {
+	return c.conn
+}
+
+var _ net.Conn = (*Conn)(nil)
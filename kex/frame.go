@@ -0,0 +1,70 @@
+package kex
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Every message is framed as
+//
+//	version (1) || type (1) || KYBER_ID (1) || length (2, big endian) || body
+//
+// and the header is authenticated as additional data of data records.
+const (
+	Version = 1
+
+	headerSize   = 5
+	maxBodySize  = 1<<16 - 1
+	maxPlaintext = 1 << 14
+)
+
+// message types
+const (
+	typeClientHello    = 1 // mode (1) || count (1) || offered KYBER_IDs || first Kex_* message
+	typeServerHello    = 2 // answer || tagB
+	typeClientFinished = 3 // tagA
+	typeData           = 4 // AEAD record
+	typeClose          = 5 // empty AEAD record, the peer will not write again
+	typeHelloRetry     = 6 // empty, asks for a client hello for another set
+)
+
+type frame struct {
+	typ     byte
+	paramID byte
+	body    []byte
+}
+
+func (f *frame) header() []byte {
+	h := make([]byte, headerSize)
+	h[0] = Version
+	h[1] = f.typ
+	h[2] = f.paramID
+	binary.BigEndian.PutUint16(h[3:], uint16(len(f.body)))
+	return h
+}
+
+func writeFrame(w io.Writer, f *frame) error {
+	if len(f.body) > maxBodySize {
+		return ErrFrameTooLarge
+	}
+	_, err := w.Write(append(f.header(), f.body...))
+	return err
+}
+
+func readFrame(r io.Reader) (*frame, []byte, error) {
+	h := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, h); err != nil {
+		return nil, nil, err
+	}
+	if h[0] != Version {
+		return nil, nil, ErrVersion
+	}
+	f := &frame{typ: h[1], paramID: h[2], body: make([]byte, binary.BigEndian.Uint16(h[3:]))}
+	if _, err := io.ReadFull(r, f.body); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, nil, err
+	}
+	return f, h, nil
+}
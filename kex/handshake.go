@@ -0,0 +1,452 @@
+// Package kex runs the UAKE and AKE key exchanges of kyber-go over a
+// net.Conn and encrypts the connection with the result, in the manner of
+// crypto/tls.
+//
+// The client offers its parameter sets in order of preference and sends
+// the first Kex_* message for the first of them. The server picks the
+// first of its own sets that the client offered; if that is not the set
+// of the client's message it asks for another one with a HelloRetry.
+// The server then answers with its message and a key confirmation tag,
+// and the client ends the handshake with its own tag. The tags and the
+// confirmed key are derived with kyber.Kex_confirm over every handshake
+// frame, offers and choice included, so a modified offer or a forged
+// HelloRetry (a downgrade) fails the handshake. The AKE mode uses the
+// transcript-bound derivation with Config.Binding.
+//
+// The confirmed key is expanded with HKDF-SHA256 into one
+// ChaCha20-Poly1305 key per direction, and data is sent in records of at
+// most 16 KiB whose nonce is a 64-bit sequence number.
+package kex
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	kyber "github.com/depressi0n/kyber-go"
+)
+
+// Mode selects the key exchange
+type Mode byte
+
+const (
+	// UAKE authenticates the server only; the client needs the server
+	// static public key
+	UAKE Mode = 1
+	// AKE authenticates both sides with their static keys
+	AKE Mode = 2
+)
+
+var (
+	ErrVersion            = errors.New("kex: unsupported protocol version")
+	ErrUnexpectedMessage  = errors.New("kex: unexpected message")
+	ErrParametersMismatch = errors.New("kex: peer uses another parameter set")
+	ErrNoCommonParameters = errors.New("kex: no parameter set in common")
+	ErrModeMismatch       = errors.New("kex: peer uses another mode")
+	ErrFrameTooLarge      = errors.New("kex: frame too large")
+	ErrDecryption         = errors.New("kex: record authentication failed")
+	ErrConfig             = errors.New("kex: invalid config")
+)
+
+// Suite is a parameter set with the static keys used with it
+type Suite struct {
+	Params *kyber.KexParameters
+
+	// StaticSecretKey is the own static secret key: always needed by
+	// the server, needed by the client in AKE mode
+	StaticSecretKey []byte
+	// PeerPublicKey is the static public key of the peer: always needed
+	// by the client, needed by the server in AKE mode
+	PeerPublicKey []byte
+}
+
+// Config configures a client or server. The mode and either Suites or
+// Params with its keys must be set; the same Config may be shared by
+// several connections
+type Config struct {
+	// Params, StaticSecretKey and PeerPublicKey set a single suite, see
+	// Suite. They are ignored if Suites is set
+	Params          *kyber.KexParameters
+	StaticSecretKey []byte
+	PeerPublicKey   []byte
+
+	// Suites lists the supported parameter sets in order of preference.
+	// The client offers them all, the server picks the first one
+	// offered by the client
+	Suites []Suite
+
+	Mode Mode
+
+	// Binding holds the identities and context bound into the AKE key,
+	// it may be nil. Both sides must use the same values
+	Binding *kyber.KexBinding
+
+	// Rand is the source of randomness, crypto/rand if nil
+	Rand io.Reader
+}
+
+func (c *Config) suites() []Suite {
+	if len(c.Suites) > 0 {
+		return c.Suites
+	}
+	if c.Params == nil {
+		return nil
+	}
+	return []Suite{{Params: c.Params, StaticSecretKey: c.StaticSecretKey, PeerPublicKey: c.PeerPublicKey}}
+}
+
+// validate returns the suites of the config, or ErrConfig if the mode
+// is unknown, there is no suite or more than 255, or a suite has no
+// parameter set. It runs before the handshake reads or writes anything
+func (c *Config) validate() ([]Suite, error) {
+	if c.Mode != UAKE && c.Mode != AKE {
+		return nil, ErrConfig
+	}
+	suites := c.suites()
+	if len(suites) == 0 || len(suites) > 255 {
+		return nil, ErrConfig
+	}
+	for i := range suites {
+		if suites[i].Params == nil || suites[i].Params.KemParams == nil {
+			return nil, ErrConfig
+		}
+	}
+	return suites, nil
+}
+
+func (c *Config) rand() io.Reader {
+	if c.Rand == nil {
+		return rand.Reader
+	}
+	return c.Rand
+}
+
+// Conn is a connection secured by the key exchange. The handshake runs
+// on the first Read or Write unless Handshake or HandshakeContext is
+// called first
+type Conn struct {
+	conn     net.Conn
+	config   *Config
+	isClient bool
+
+	handshakeMutex sync.Mutex
+	handshakeErr   error
+	handshakeDone  atomic.Bool
+
+	params     *kyber.KexParameters // negotiated parameter set
+	transcript []byte               // handshake frames so far
+
+	in, out halfConn
+}
+
+// Client returns a client side Conn over conn
+func Client(conn net.Conn, config *Config) *Conn {
+	return &Conn{conn: conn, config: config, isClient: true}
+}
+
+// Server returns a server side Conn over conn
+func Server(conn net.Conn, config *Config) *Conn {
+	return &Conn{conn: conn, config: config}
+}
+
+// Handshake runs the handshake if it has not run yet
+func (c *Conn) Handshake() error {
+	return c.HandshakeContext(context.Background())
+}
+
+/*************************************************
+* Name:        HandshakeContext
+*
+* Description: Runs the handshake if it has not run yet. If ctx is
+*              done before the handshake ends, the handshake is
+*              aborted and the underlying connection closed
+*
+* Arguments:   - ctx context.Context: handshake deadline and
+*                cancellation
+*
+* Returns      - err error: ctx.Err() if ctx ended the handshake,
+*                the handshake error otherwise. Once the handshake has
+*                failed every call returns the same error
+**************************************************/
+func (c *Conn) HandshakeContext(ctx context.Context) (ret error) {
+	if c.handshakeDone.Load() {
+		return nil
+	}
+	c.handshakeMutex.Lock()
+	defer c.handshakeMutex.Unlock()
+	if c.handshakeDone.Load() || c.handshakeErr != nil {
+		return c.handshakeErr
+	}
+
+	if ctx.Done() != nil {
+		done := make(chan struct{})
+		interrupted := make(chan error, 1)
+		defer func() {
+			close(done)
+			if err := <-interrupted; err != nil {
+				// the connection was closed, even if the handshake got through
+				ret = err
+				c.handshakeErr = err
+				c.handshakeDone.Store(false)
+			}
+		}()
+		go func() {
+			select {
+			case <-ctx.Done():
+				c.conn.Close()
+				interrupted <- ctx.Err()
+			case <-done:
+				interrupted <- nil
+			}
+		}()
+	}
+
+	var ck []byte
+	if c.isClient {
+		ck, c.handshakeErr = c.clientHandshake()
+	} else {
+		ck, c.handshakeErr = c.serverHandshake()
+	}
+	if c.handshakeErr != nil {
+		return c.handshakeErr
+	}
+	if c.handshakeErr = c.setKeys(ck); c.handshakeErr == nil {
+		c.handshakeDone.Store(true)
+	}
+	return c.handshakeErr
+}
+
+// Parameters returns the negotiated parameter set, nil before the
+// handshake completes
+func (c *Conn) Parameters() *kyber.KexParameters {
+	if !c.handshakeDone.Load() {
+		return nil
+	}
+	return c.params
+}
+
+func (c *Conn) paramID() byte {
+	return c.params.KemParams.KYBER_ID
+}
+
+func (c *Conn) writeHandshake(f *frame) error {
+	if err := writeFrame(c.conn, f); err != nil {
+		return err
+	}
+	c.transcript = append(append(c.transcript, f.header()...), f.body...)
+	return nil
+}
+
+func (c *Conn) readHandshake() (*frame, error) {
+	f, header, err := readFrame(c.conn)
+	if err != nil {
+		return nil, err
+	}
+	c.transcript = append(append(c.transcript, header...), f.body...)
+	return f, nil
+}
+
+// confirm derives the confirmed key and both tags from k over the
+// handshake frames up to the last client hello and the server answer
+func confirm(k []byte, clientTranscript []byte, paramID byte, sendb []byte) ([]byte, []byte, []byte) {
+	ck, tagA, tagB := kyber.Kex_confirm(k, clientTranscript, append([]byte{paramID}, sendb...))
+	for i := range k {
+		k[i] = 0
+	}
+	return ck, tagA, tagB
+}
+
+func findSuite(suites []Suite, id byte) *Suite {
+	for i := range suites {
+		if suites[i].Params.KemParams.KYBER_ID == id {
+			return &suites[i]
+		}
+	}
+	return nil
+}
+
+func (c *Conn) clientHello(suite *Suite, offers []byte) (*kyber.Initiator, error) {
+	config := c.config
+	var a *kyber.Initiator
+	var err error
+	switch config.Mode {
+	case UAKE:
+		a, err = kyber.NewUAKEInitiator(suite.Params, suite.PeerPublicKey)
+	case AKE:
+		a, err = kyber.NewBoundAKEInitiator(suite.Params, suite.PeerPublicKey, suite.StaticSecretKey, config.Binding)
+	default:
+		return nil, ErrConfig
+	}
+	if err != nil {
+		return nil, err
+	}
+	senda, err := a.StartRand(config.rand())
+	if err != nil {
+		return nil, err
+	}
+	body := append([]byte{byte(config.Mode), byte(len(offers))}, offers...)
+	body = append(body, senda...)
+	c.params = suite.Params
+	if err := c.writeHandshake(&frame{typ: typeClientHello, paramID: c.paramID(), body: body}); err != nil {
+		a.Abort()
+		return nil, err
+	}
+	return a, nil
+}
+
+func (c *Conn) clientHandshake() ([]byte, error) {
+	suites, err := c.config.validate()
+	if err != nil {
+		return nil, err
+	}
+	offers := make([]byte, len(suites))
+	for i := range suites {
+		offers[i] = suites[i].Params.KemParams.KYBER_ID
+	}
+	a, err := c.clientHello(&suites[0], offers)
+	if err != nil {
+		return nil, err
+	}
+	// zero the ephemeral secrets if the handshake fails before Finish
+	defer func() {
+		if a != nil {
+			a.Abort()
+		}
+	}()
+
+	f, err := c.readHandshake()
+	if err != nil {
+		return nil, err
+	}
+	if f.typ == typeHelloRetry {
+		// only one retry, for another set that was offered
+		suite := findSuite(suites, f.paramID)
+		if suite == nil || f.paramID == c.paramID() || len(f.body) != 0 {
+			return nil, ErrUnexpectedMessage
+		}
+		a.Abort()
+		if a, err = c.clientHello(suite, offers); err != nil {
+			return nil, err
+		}
+		if f, err = c.readHandshake(); err != nil {
+			return nil, err
+		}
+	}
+	if f.typ != typeServerHello {
+		return nil, ErrUnexpectedMessage
+	}
+	if f.paramID != c.paramID() {
+		return nil, ErrParametersMismatch
+	}
+	body := f.body
+	if len(body) < kyber.KEX_CONFIRMBYTES {
+		return nil, kyber.ErrInvalidKexMessageLength
+	}
+	n := len(body) - kyber.KEX_CONFIRMBYTES
+	clientTranscript := c.transcript[:len(c.transcript)-headerSize-len(body)]
+	k, err := a.Finish(body[:n])
+	if err != nil {
+		return nil, err
+	}
+	ck, tagA, tagB := confirm(k, clientTranscript, c.paramID(), body[:n])
+	if err := kyber.Kex_confirm_verify(body[n:], tagB); err != nil {
+		return nil, err
+	}
+	if err := c.writeHandshake(&frame{typ: typeClientFinished, paramID: c.paramID(), body: tagA}); err != nil {
+		return nil, err
+	}
+	return ck, nil
+}
+
+// readClientHello reads a client hello and returns the offered sets and
+// the first Kex_* message
+func (c *Conn) readClientHello() (*frame, []byte, []byte, error) {
+	f, err := c.readHandshake()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if f.typ != typeClientHello {
+		return nil, nil, nil, ErrUnexpectedMessage
+	}
+	if len(f.body) < 2 || len(f.body) < 2+int(f.body[1]) {
+		return nil, nil, nil, kyber.ErrInvalidKexMessageLength
+	}
+	if Mode(f.body[0]) != c.config.Mode {
+		return nil, nil, nil, ErrModeMismatch
+	}
+	n := 2 + int(f.body[1])
+	return f, f.body[2:n], f.body[n:], nil
+}
+
+func (c *Conn) serverHandshake() ([]byte, error) {
+	config := c.config
+	suites, err := config.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	f, offers, senda, err := c.readClientHello()
+	if err != nil {
+		return nil, err
+	}
+	// server preference among the offered sets
+	var suite *Suite
+	for i := range suites {
+		if bytes.IndexByte(offers, suites[i].Params.KemParams.KYBER_ID) >= 0 {
+			suite = &suites[i]
+			break
+		}
+	}
+	if suite == nil {
+		return nil, ErrNoCommonParameters
+	}
+	c.params = suite.Params
+	if f.paramID != c.paramID() {
+		if err := c.writeHandshake(&frame{typ: typeHelloRetry, paramID: c.paramID()}); err != nil {
+			return nil, err
+		}
+		var retryOffers []byte
+		if f, retryOffers, senda, err = c.readClientHello(); err != nil {
+			return nil, err
+		}
+		if f.paramID != c.paramID() || !bytes.Equal(retryOffers, offers) {
+			return nil, ErrParametersMismatch
+		}
+	}
+	clientTranscript := append([]byte{}, c.transcript...)
+
+	var b *kyber.Responder
+	if config.Mode == UAKE {
+		b, err = kyber.NewUAKEResponder(suite.Params, suite.StaticSecretKey)
+	} else {
+		b, err = kyber.NewBoundAKEResponder(suite.Params, suite.StaticSecretKey, suite.PeerPublicKey, config.Binding)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sendb, k, err := b.RespondRand(senda, config.rand())
+	if err != nil {
+		return nil, err
+	}
+	ck, tagA, tagB := confirm(k, clientTranscript, c.paramID(), sendb)
+	if err := c.writeHandshake(&frame{typ: typeServerHello, paramID: c.paramID(), body: append(sendb, tagB...)}); err != nil {
+		return nil, err
+	}
+
+	f, err = c.readHandshake()
+	if err != nil {
+		return nil, err
+	}
+	if f.typ != typeClientFinished || f.paramID != c.paramID() {
+		return nil, ErrUnexpectedMessage
+	}
+	if err := kyber.Kex_confirm_verify(f.body, tagA); err != nil {
+		return nil, err
+	}
+	return ck, nil
+}
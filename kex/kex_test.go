@@ -0,0 +1,254 @@
+package kex
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	kyber "github.com/depressi0n/kyber-go"
+)
+
+type keys struct {
+	kexpp      *kyber.KexParameters
+	pka, ska   []byte
+	pkb, skb   []byte
+	clientConf *Config
+	serverConf *Config
+}
+
+func newKeys(mode Mode) *keys {
+	k := &keys{kexpp: kyber.NewKexParameters(3)}
+	k.pka, k.ska = kyber.Crypto_kem_keypair(k.kexpp.KemParams)
+	k.pkb, k.skb = kyber.Crypto_kem_keypair(k.kexpp.KemParams)
+	binding := &kyber.KexBinding{IdentityA: []byte("client"), IdentityB: []byte("server")}
+	k.clientConf = &Config{Params: k.kexpp, Mode: mode, PeerPublicKey: k.pkb, Binding: binding}
+	k.serverConf = &Config{Params: k.kexpp, Mode: mode, StaticSecretKey: k.skb, Binding: binding}
+	if mode == AKE {
+		k.clientConf.StaticSecretKey = k.ska
+		k.serverConf.PeerPublicKey = k.pka
+	}
+	return k
+}
+
+// handshake runs both sides over net.Pipe, closing the side that fails so
+// that the other one does not block
+func handshake(clientConf, serverConf *Config) (*Conn, *Conn, error, error) {
+	c1, c2 := net.Pipe()
+	client, server := Client(c1, clientConf), Server(c2, serverConf)
+	serverErr := make(chan error, 1)
+	go func() {
+		err := server.Handshake()
+		if err != nil {
+			c2.Close()
+		}
+		serverErr <- err
+	}()
+	err := client.Handshake()
+	if err != nil {
+		c1.Close()
+	}
+	return client, server, err, <-serverErr
+}
+
+func TestConn(t *testing.T) {
+	for _, mode := range []Mode{UAKE, AKE} {
+		k := newKeys(mode)
+		client, server, cerr, serr := handshake(k.clientConf, k.serverConf)
+		if cerr != nil || serr != nil {
+			t.Fatalf("mode %d: handshake: %v, %v", mode, cerr, serr)
+		}
+
+		// the server echoes while the client writes several records
+		go io.Copy(server, server)
+		msg := make([]byte, 3*maxPlaintext+123)
+		rand.Read(msg)
+		writeErr := make(chan error, 1)
+		go func() {
+			_, err := client.Write(msg)
+			writeErr <- err
+		}()
+		got := make([]byte, len(msg))
+		if _, err := io.ReadFull(client, got); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-writeErr; err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, msg) {
+			t.Errorf("mode %d: echoed data differs", mode)
+		}
+
+		if err := client.Close(); err != nil {
+			t.Errorf("mode %d: Close: %v", mode, err)
+		}
+		if _, err := client.Write([]byte("x")); err == nil {
+			t.Errorf("mode %d: write after Close accepted", mode)
+		}
+	}
+}
+
+func TestCloseNotify(t *testing.T) {
+	k := newKeys(UAKE)
+	client, server, _, _ := handshake(k.clientConf, k.serverConf)
+	go func() {
+		client.Write([]byte("bye"))
+		client.Close()
+	}()
+	if got, err := io.ReadAll(server); err != nil || string(got) != "bye" {
+		t.Errorf("ReadAll = %q, %v", got, err)
+	}
+
+	// a connection closed without the close record is truncated
+	client, server, _, _ = handshake(k.clientConf, k.serverConf)
+	go client.NetConn().Close()
+	if _, err := server.Read(make([]byte, 1)); err != io.ErrUnexpectedEOF {
+		t.Errorf("truncated: got %v", err)
+	}
+}
+
+func TestTamperedRecord(t *testing.T) {
+	k := newKeys(AKE)
+	client, server, _, _ := handshake(k.clientConf, k.serverConf)
+	f := &frame{typ: typeData, paramID: k.kexpp.KemParams.KYBER_ID, body: make([]byte, 32)}
+	go writeFrame(client.NetConn(), f)
+	if _, err := server.Read(make([]byte, 1)); !errors.Is(err, ErrDecryption) {
+		t.Errorf("forged record: got %v", err)
+	}
+	if _, err := server.Read(make([]byte, 1)); !errors.Is(err, ErrDecryption) {
+		t.Errorf("read after failure: got %v", err)
+	}
+}
+
+func TestReadTimeout(t *testing.T) {
+	k := newKeys(UAKE)
+	client, server, _, _ := handshake(k.clientConf, k.serverConf)
+
+	// take a record of the server off the wire
+	go server.Write([]byte("hello"))
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(client.NetConn(), header); err != nil {
+		t.Fatal(err)
+	}
+	record := append(header, make([]byte, int(header[3])<<8|int(header[4]))...)
+	if _, err := io.ReadFull(client.NetConn(), record[headerSize:]); err != nil {
+		t.Fatal(err)
+	}
+
+	// the deadline expires with nothing and then with part of the record
+	for _, part := range [][]byte{nil, record[:3], record[3:10]} {
+		go server.NetConn().Write(part)
+		client.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+		_, err := client.Read(make([]byte, 5))
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Fatalf("got %v, want a timeout", err)
+		}
+	}
+	go server.NetConn().Write(record[10:])
+	client.SetReadDeadline(time.Time{})
+	got := make([]byte, 5)
+	if _, err := io.ReadFull(client, got); err != nil || string(got) != "hello" {
+		t.Errorf("read after timeouts: %q, %v", got, err)
+	}
+}
+
+func TestHandshakeErrors(t *testing.T) {
+	k := newKeys(AKE)
+
+	// a server with Kyber512 keys
+	other := *k.serverConf
+	other.Params = kyber.NewKexParameters(2)
+	other.PeerPublicKey, _ = kyber.Crypto_kem_keypair(other.Params.KemParams)
+	_, other.StaticSecretKey = kyber.Crypto_kem_keypair(other.Params.KemParams)
+	if _, _, _, serr := handshake(k.clientConf, &other); !errors.Is(serr, ErrNoCommonParameters) {
+		t.Errorf("parameter mismatch: server got %v", serr)
+	}
+
+	other = *k.serverConf
+	other.Mode = UAKE
+	if _, _, _, serr := handshake(k.clientConf, &other); !errors.Is(serr, ErrModeMismatch) {
+		t.Errorf("mode mismatch: server got %v", serr)
+	}
+
+	other = *k.serverConf
+	other.Binding = &kyber.KexBinding{IdentityA: []byte("mallory"), IdentityB: []byte("server")}
+	if _, _, cerr, _ := handshake(k.clientConf, &other); !errors.Is(cerr, kyber.ErrKexConfirmation) {
+		t.Errorf("binding mismatch: client got %v", cerr)
+	}
+
+	// a UAKE client talking to an impostor without the server key
+	u := newKeys(UAKE)
+	_, impostor := kyber.Crypto_kem_keypair(u.kexpp.KemParams)
+	other = *u.serverConf
+	other.StaticSecretKey = impostor
+	if _, _, cerr, _ := handshake(u.clientConf, &other); !errors.Is(cerr, kyber.ErrKexConfirmation) {
+		t.Errorf("impostor: client got %v", cerr)
+	}
+
+	bad := *u.clientConf
+	bad.Mode = 0
+	if _, _, cerr, _ := handshake(&bad, u.serverConf); !errors.Is(cerr, ErrConfig) {
+		t.Errorf("invalid mode: client got %v", cerr)
+	}
+}
+
+// TestInvalidConfig checks that invalid configs fail with ErrConfig before
+// the handshake reads or writes: the peer end of the pipe is never used
+func TestInvalidConfig(t *testing.T) {
+	k := newKeys(UAKE)
+	for _, tc := range []struct {
+		name     string
+		isClient bool
+		config   *Config
+	}{
+		{"client without suites", true, &Config{Mode: UAKE}},
+		{"server without suites", false, &Config{Mode: UAKE}},
+		{"client suite without params", true, &Config{Mode: UAKE, Suites: []Suite{{PeerPublicKey: k.pkb}}}},
+		{"server suite without params", false, &Config{Mode: UAKE, Suites: []Suite{
+			{Params: k.kexpp, StaticSecretKey: k.skb}, {StaticSecretKey: k.skb},
+		}}},
+		{"server without mode", false, &Config{Params: k.kexpp, StaticSecretKey: k.skb}},
+	} {
+		c1, c2 := net.Pipe()
+		var conn *Conn
+		if tc.isClient {
+			conn = Client(c1, tc.config)
+		} else {
+			conn = Server(c1, tc.config)
+		}
+		done := make(chan error, 1)
+		go func() { done <- conn.Handshake() }()
+		select {
+		case err := <-done:
+			if !errors.Is(err, ErrConfig) {
+				t.Errorf("%s: got %v", tc.name, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("%s: handshake blocked on the connection", tc.name)
+		}
+		c1.Close()
+		c2.Close()
+	}
+}
+
+func TestHandshakeContext(t *testing.T) {
+	k := newKeys(UAKE)
+	c1, c2 := net.Pipe()
+	defer c2.Close()
+	client := Client(c1, k.clientConf)
+
+	// nobody answers
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := client.HandshakeContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("timeout: got %v", err)
+	}
+	if _, err := client.Write([]byte("x")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("write after failed handshake: got %v", err)
+	}
+}
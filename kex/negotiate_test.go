@@ -0,0 +1,149 @@
+package kex
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	kyber "github.com/depressi0n/kyber-go"
+)
+
+// suites returns client and server suites for AKE over the given sets,
+// with one key pair per side and set
+func suites(sets ...*kyber.KexParameters) (map[byte]Suite, map[byte]Suite) {
+	client, server := map[byte]Suite{}, map[byte]Suite{}
+	for _, params := range sets {
+		pka, ska := kyber.Crypto_kem_keypair(params.KemParams)
+		pkb, skb := kyber.Crypto_kem_keypair(params.KemParams)
+		id := params.KemParams.KYBER_ID
+		client[id] = Suite{Params: params, StaticSecretKey: ska, PeerPublicKey: pkb}
+		server[id] = Suite{Params: params, StaticSecretKey: skb, PeerPublicKey: pka}
+	}
+	return client, server
+}
+
+func pick(all map[byte]Suite, sets ...*kyber.KexParameters) []Suite {
+	var s []Suite
+	for _, params := range sets {
+		s = append(s, all[params.KemParams.KYBER_ID])
+	}
+	return s
+}
+
+func TestNegotiation(t *testing.T) {
+	kyber512, kyber768 := kyber.NewKexParameters(2), kyber.NewKexParameters(3)
+	mlkem768, mlkem1024 := kyber.NewMLKEMKexParameters(3), kyber.NewMLKEMKexParameters(4)
+	cs, ss := suites(kyber512, kyber768, mlkem768, mlkem1024)
+
+	for _, tc := range []struct {
+		name           string
+		client, server []*kyber.KexParameters
+		want           *kyber.KexParameters
+	}{
+		{"first choice", []*kyber.KexParameters{mlkem768, kyber768}, []*kyber.KexParameters{mlkem1024, mlkem768}, mlkem768},
+		{"retry", []*kyber.KexParameters{kyber512, mlkem1024}, []*kyber.KexParameters{mlkem1024, kyber512}, mlkem1024},
+		{"server order", []*kyber.KexParameters{kyber512, kyber768, mlkem768}, []*kyber.KexParameters{mlkem768, kyber768}, mlkem768},
+	} {
+		for _, mode := range []Mode{UAKE, AKE} {
+			clientConf := &Config{Mode: mode, Suites: pick(cs, tc.client...)}
+			serverConf := &Config{Mode: mode, Suites: pick(ss, tc.server...)}
+			client, server, cerr, serr := handshake(clientConf, serverConf)
+			if cerr != nil || serr != nil {
+				t.Fatalf("%s mode %d: %v, %v", tc.name, mode, cerr, serr)
+			}
+			if client.Parameters() != tc.want || server.Parameters() != tc.want {
+				t.Errorf("%s mode %d: negotiated %s", tc.name, mode, client.Parameters().KemParams.KYBER_NAME)
+			}
+			go func() {
+				client.Write([]byte("ping"))
+				client.Close()
+			}()
+			buf := make([]byte, 4)
+			if n, err := server.Read(buf); err != nil || string(buf[:n]) != "ping" {
+				t.Errorf("%s mode %d: Read = %q, %v", tc.name, mode, buf[:n], err)
+			}
+		}
+	}
+
+	clientConf := &Config{Mode: UAKE, Suites: pick(cs, kyber512)}
+	serverConf := &Config{Mode: UAKE, Suites: pick(ss, mlkem768, mlkem1024)}
+	if _, _, _, serr := handshake(clientConf, serverConf); !errors.Is(serr, ErrNoCommonParameters) {
+		t.Errorf("no common set: server got %v", serr)
+	}
+}
+
+// mitm forwards frames between client and server, letting modify change
+// or drop (nil) frames from the client and inject answers of its own
+func mitm(clientSide, serverSide net.Conn, modify func(n int, f *frame) (toServer, toClient *frame)) {
+	go func() {
+		for {
+			f, _, err := readFrame(serverSide)
+			if err != nil {
+				clientSide.Close()
+				return
+			}
+			writeFrame(clientSide, f)
+		}
+	}()
+	for n := 0; ; n++ {
+		f, _, err := readFrame(clientSide)
+		if err != nil {
+			serverSide.Close()
+			return
+		}
+		toServer, toClient := modify(n, f)
+		if toServer != nil {
+			writeFrame(serverSide, toServer)
+		}
+		if toClient != nil {
+			writeFrame(clientSide, toClient)
+		}
+	}
+}
+
+func runMITM(clientConf, serverConf *Config, modify func(n int, f *frame) (*frame, *frame)) (error, error) {
+	c1, p1 := net.Pipe()
+	p2, c2 := net.Pipe()
+	go mitm(p1, p2, modify)
+	serverErr := make(chan error, 1)
+	go func() {
+		err := Server(c2, serverConf).Handshake()
+		c2.Close()
+		serverErr <- err
+	}()
+	err := Client(c1, clientConf).Handshake()
+	c1.Close()
+	return err, <-serverErr
+}
+
+func TestDowngrade(t *testing.T) {
+	kyber512, mlkem1024 := kyber.NewKexParameters(2), kyber.NewMLKEMKexParameters(4)
+	cs, ss := suites(kyber512, mlkem1024)
+	clientConf := &Config{Mode: AKE, Suites: pick(cs, mlkem1024, kyber512)}
+	serverConf := &Config{Mode: AKE, Suites: pick(ss, mlkem1024, kyber512)}
+	weak := kyber512.KemParams.KYBER_ID
+
+	// the offers are cut down to Kyber512 in every client hello: the server
+	// asks for it and the client complies, but the transcripts differ
+	cerr, _ := runMITM(clientConf, serverConf, func(n int, f *frame) (*frame, *frame) {
+		if f.typ == typeClientHello {
+			f.body = append([]byte{f.body[0], 1, weak}, f.body[2+f.body[1]:]...)
+		}
+		return f, nil
+	})
+	if !errors.Is(cerr, kyber.ErrKexConfirmation) {
+		t.Errorf("modified offers: client got %v", cerr)
+	}
+
+	// a forged HelloRetry for Kyber512: the server still prefers ML-KEM-1024
+	// and asks for it again, which the client refuses
+	cerr, _ = runMITM(clientConf, serverConf, func(n int, f *frame) (*frame, *frame) {
+		if n == 0 {
+			return nil, &frame{typ: typeHelloRetry, paramID: weak}
+		}
+		return f, nil
+	})
+	if !errors.Is(cerr, ErrUnexpectedMessage) {
+		t.Errorf("forged HelloRetry: client got %v", cerr)
+	}
+}
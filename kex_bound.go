@@ -0,0 +1,132 @@
+package kyber
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+
+	"golang.org/x/crypto/sha3"
+)
+
+/* Transcript-bound AKE. The messages are those of Kex_ake_*, but the
+ * session key is
+ *   SHAKE256(label || lp(Context) || lp(IdentityA) || lp(IdentityB) ||
+ *            lp(pka) || lp(pkb) || lp(sendA) || lp(sendB) || ss1||ss2||ss3)
+ * where lp(x) is the 4-byte big-endian length of x followed by x, so a key
+ * is only shared by parties that agree on who talked to whom, with which
+ * static keys, in which context and over which messages. */
+const kexTranscriptLabel = "kyber-go AKE transcript v1"
+
+// KexBinding holds the optional identities and context label bound into
+// the session key of the transcript-bound AKE
+type KexBinding struct {
+	IdentityA []byte
+	IdentityB []byte
+	Context   []byte
+}
+
+func kex_transcript_kdf(k []byte, ss []byte, binding *KexBinding, pka, pkb, senda, sendb []byte) {
+	if binding == nil {
+		binding = &KexBinding{}
+	}
+	h := sha3.NewShake256()
+	h.Write([]byte(kexTranscriptLabel))
+	for _, b := range [][]byte{binding.Context, binding.IdentityA, binding.IdentityB, pka, pkb, senda, sendb} {
+		var l [4]byte
+		binary.BigEndian.PutUint32(l[:], uint32(len(b)))
+		h.Write(l[:])
+		h.Write(b)
+	}
+	h.Write(ss)
+	h.Read(k)
+}
+
+// kex_sk_pk returns the public key embedded in a secret key
+func kex_sk_pk(kexpp *KexParameters, sk []byte) []byte {
+	off := kexpp.KemParams.KYBER_INDCPA_SECRETKEYBYTES
+	return sk[off : off+kexpp.CRYPTO_PUBLICKEYBYTES]
+}
+
+/*************************************************
+* Name:        Kex_ake_sharedB_bound
+*
+* Description: Kex_ake_sharedB_bound_rand with crypto/rand. recv is
+*              bound into the key as is, so unlike Kex_ake_sharedB it
+*              must be exactly KEX_AKE_SENDABYTES bytes long
+*
+* Arguments:   - kexpp *KexParameters: Kex parameters struct
+*              - recv []byte: message of A (Kex_ake_initA)
+*              - skb []byte: static secret key of B
+*              - pka []byte: static public key of A
+*              - binding *KexBinding: identities and context, may be nil
+*
+* Returns      - send []byte: output message of KEX_AKE_SENDBBYTES bytes
+*              - k []byte: output session key of KEX_SSBYTES bytes
+*              - err error: non-nil on bad input or RNG failure
+**************************************************/
+func Kex_ake_sharedB_bound(kexpp *KexParameters, recv []byte, skb []byte, pka []byte, binding *KexBinding) ([]byte, []byte, error) {
+	return Kex_ake_sharedB_bound_rand(kexpp, recv, skb, pka, binding, rand.Reader)
+}
+
+/*************************************************
+* Name:        Kex_ake_sharedB_bound_rand
+*
+* Description: Answers the first AKE message like Kex_ake_sharedB_rand,
+*              deriving the session key over the whole transcript
+*
+* Arguments:   - kexpp *KexParameters: Kex parameters struct
+*              - recv []byte: message of A (Kex_ake_initA)
+*              - skb []byte: static secret key of B
+*              - pka []byte: static public key of A
+*              - binding *KexBinding: identities and context, may be nil
+*              - rand io.Reader: source of randomness
+*
+* Returns      - send []byte: output message of KEX_AKE_SENDBBYTES bytes
+*              - k []byte: output session key of KEX_SSBYTES bytes
+*              - err error: non-nil on bad input or RNG failure
+**************************************************/
+func Kex_ake_sharedB_bound_rand(kexpp *KexParameters, recv []byte, skb []byte, pka []byte, binding *KexBinding, rand io.Reader) ([]byte, []byte, error) {
+	if len(skb) != kexpp.CRYPTO_SECRETKEYBYTES {
+		return nil, nil, ErrInvalidSecretKeyLength
+	}
+	send, ss, err := kex_ake_sharedB_ss(kexpp, recv, skb, pka, rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	k := make([]byte, KEX_SSBYTES)
+	kex_transcript_kdf(k, ss, binding, pka, kex_sk_pk(kexpp, skb), recv, send)
+	return send, k, nil
+}
+
+/*************************************************
+* Name:        Kex_ake_sharedA_bound
+*
+* Description: Finishes the AKE like Kex_ake_sharedA, deriving the
+*              session key over the whole transcript
+*
+* Arguments:   - kexpp *KexParameters: Kex parameters struct
+*              - send []byte: message A sent (Kex_ake_initA)
+*              - recv []byte: message of B
+*              - tk []byte: tk of Kex_ake_initA
+*              - sk []byte: ephemeral secret key of Kex_ake_initA
+*              - ska []byte: static secret key of A
+*              - pkb []byte: static public key of B
+*              - binding *KexBinding: identities and context, may be nil
+*
+* Returns      - k []byte: output session key of KEX_SSBYTES bytes
+*              - err error: non-nil on bad input lengths
+**************************************************/
+func Kex_ake_sharedA_bound(kexpp *KexParameters, send []byte, recv []byte, tk []byte, sk []byte, ska []byte, pkb []byte, binding *KexBinding) ([]byte, error) {
+	if len(send) != kexpp.KEX_AKE_SENDABYTES || len(recv) != kexpp.KEX_AKE_SENDBBYTES {
+		return nil, ErrInvalidKexMessageLength
+	}
+	if len(sk) != kexpp.CRYPTO_SECRETKEYBYTES || len(ska) != kexpp.CRYPTO_SECRETKEYBYTES || len(tk) != CRYPTO_BYTES {
+		return nil, ErrInvalidSecretKeyLength
+	}
+	if len(pkb) != kexpp.CRYPTO_PUBLICKEYBYTES {
+		return nil, ErrInvalidPublicKeyLength
+	}
+	k := make([]byte, KEX_SSBYTES)
+	kex_transcript_kdf(k, kex_ake_sharedA_ss(kexpp, recv, tk, sk, ska), binding, kex_sk_pk(kexpp, ska), pkb, send, recv)
+	return k, nil
+}
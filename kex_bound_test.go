@@ -0,0 +1,94 @@
+package kyber
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func bound_ake(t *testing.T, kexpp *KexParameters, pka, ska, pkb, skb []byte, ba, bb *KexBinding) ([]byte, []byte) {
+	senda, tk, eska, err := Kex_ake_initA_rand(kexpp, pkb, newTestRand("alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sendb, kb, err := Kex_ake_sharedB_bound_rand(kexpp, senda, skb, pka, bb, newTestRand("bob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ka, err := Kex_ake_sharedA_bound(kexpp, senda, sendb, tk, eska, ska, pkb, ba)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ka, kb
+}
+
+func Test_KexBound(t *testing.T) {
+	kexpp := NewKexParameters(3)
+	pka, ska, _ := Crypto_kem_keypair_rand(kexpp.KemParams, newTestRand("alice static"))
+	pkb, skb, _ := Crypto_kem_keypair_rand(kexpp.KemParams, newTestRand("bob static"))
+	binding := &KexBinding{IdentityA: []byte("alice"), IdentityB: []byte("bob"), Context: []byte("test")}
+
+	ka, kb := bound_ake(t, kexpp, pka, ska, pkb, skb, binding, binding)
+	if !bytes.Equal(ka, kb) {
+		t.Fatal("bound AKE keys differ")
+	}
+	// regression value, the transcript derivation has no external vectors
+	if sum := sha256.Sum256(ka); hex.EncodeToString(sum[:]) != "6e5e22baf491c069b234fef169f220cddea029aa03a48912be66711a1a45cfcb" {
+		t.Errorf("bound AKE key digest %x", sum)
+	}
+
+	// same messages as the unbound AKE, but another key
+	senda, tk, eska, _ := Kex_ake_initA_rand(kexpp, pkb, newTestRand("alice"))
+	sendb, _, _ := Kex_ake_sharedB_rand(kexpp, senda, skb, pka, newTestRand("bob"))
+	if bytes.Equal(Kex_ake_sharedA(kexpp, sendb, tk, eska, ska), ka) {
+		t.Error("bound key equals unbound key")
+	}
+
+	if ka, kb := bound_ake(t, kexpp, pka, ska, pkb, skb, nil, &KexBinding{}); !bytes.Equal(ka, kb) {
+		t.Error("nil and empty binding differ")
+	}
+	for name, other := range map[string]*KexBinding{
+		"identity A": {IdentityA: []byte("mallory"), IdentityB: []byte("bob"), Context: []byte("test")},
+		"identity B": {IdentityA: []byte("alice"), IdentityB: []byte("mallory"), Context: []byte("test")},
+		"context":    {IdentityA: []byte("alice"), IdentityB: []byte("bob"), Context: []byte("prod")},
+		"swapped":    {IdentityA: []byte("bob"), IdentityB: []byte("alice"), Context: []byte("test")},
+		"shifted":    {IdentityA: []byte("alic"), IdentityB: []byte("ebob"), Context: []byte("test")},
+	} {
+		if ka, kb := bound_ake(t, kexpp, pka, ska, pkb, skb, binding, other); bytes.Equal(ka, kb) {
+			t.Errorf("%s mismatch not detected", name)
+		}
+	}
+
+	// sessions
+	a, _ := NewBoundAKEInitiator(kexpp, pkb, ska, binding)
+	b, _ := NewBoundAKEResponder(kexpp, skb, pka, binding)
+	m1, _ := a.StartRand(newTestRand("alice"))
+	m2, kb2, err := b.RespondRand(m1, newTestRand("bob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ka2, err := a.Finish(m2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ka2, ka) || !bytes.Equal(kb2, ka) {
+		t.Error("bound sessions disagree with Kex_ake_*_bound")
+	}
+
+	if _, err := Kex_ake_sharedA_bound(kexpp, senda[1:], sendb, tk, eska, ska, pkb, nil); err != ErrInvalidKexMessageLength {
+		t.Errorf("short send: got %v", err)
+	}
+	if _, err := Kex_ake_sharedA_bound(kexpp, senda, sendb, tk, eska, ska, pkb[1:], nil); err != ErrInvalidPublicKeyLength {
+		t.Errorf("short pkb: got %v", err)
+	}
+	if _, _, err := Kex_ake_sharedB_bound(kexpp, senda[1:], skb, pka, nil); err != ErrInvalidKexMessageLength {
+		t.Errorf("short recv: got %v", err)
+	}
+	if _, _, err := Kex_ake_sharedB_bound(kexpp, append(senda, 0), skb, pka, nil); err != ErrInvalidKexMessageLength {
+		t.Errorf("long recv: got %v", err)
+	}
+	if _, kb, err := Kex_ake_sharedB_bound(kexpp, senda, skb, pka, nil); err != nil || len(kb) != KEX_SSBYTES {
+		t.Errorf("Kex_ake_sharedB_bound: %v", err)
+	}
+}
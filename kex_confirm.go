@@ -0,0 +1,75 @@
+package kyber
+
+import (
+	"crypto/hmac"
+	"crypto/subtle"
+	"encoding/binary"
+
+	"golang.org/x/crypto/sha3"
+)
+
+/* Key confirmation for any of the Kex_* flows. With th the transcript hash
+ *   th  = SHA3-256(lp(sendA) || lp(sendB))
+ *   okm = SHAKE256(label || k || th) = kA (32) || kB (32) || ck (32)
+ * B sends tagB = HMAC-SHA3-256(kB, "B" || th) with its answer, A checks it
+ * and sends tagA = HMAC-SHA3-256(kA, "A" || th) as a third flight. Each
+ * side uses the confirmed key ck only once the peer's tag checks, so a
+ * failed decapsulation (which yields a random key) is detected. */
+const (
+	KEX_CONFIRMBYTES = 32
+
+	kexConfirmLabel = "kyber-go KEX confirm v1"
+)
+
+/*************************************************
+* Name:        Kex_confirm
+*
+* Description: Derives the confirmed session key and both
+*              confirmation tags from a Kex_* session key
+*
+* Arguments:   - k []byte: session key of a Kex_* flow
+*              - senda []byte: message of A
+*              - sendb []byte: message of B
+*
+* Returns      - ck []byte: confirmed session key of KEX_SSBYTES bytes
+*              - tagA []byte: tag sent by A, KEX_CONFIRMBYTES bytes
+*              - tagB []byte: tag sent by B, KEX_CONFIRMBYTES bytes
+**************************************************/
+func Kex_confirm(k []byte, senda []byte, sendb []byte) ([]byte, []byte, []byte) {
+	th := sha3.New256()
+	for _, m := range [][]byte{senda, sendb} {
+		var l [4]byte
+		binary.BigEndian.PutUint32(l[:], uint32(len(m)))
+		th.Write(l[:])
+		th.Write(m)
+	}
+	transcript := th.Sum(nil)
+
+	okm := make([]byte, 2*KEX_CONFIRMBYTES+KEX_SSBYTES)
+	h := sha3.NewShake256()
+	h.Write([]byte(kexConfirmLabel))
+	h.Write(k)
+	h.Write(transcript)
+	h.Read(okm)
+
+	tag := func(key []byte, role string) []byte {
+		mac := hmac.New(sha3.New256, key)
+		mac.Write([]byte(role))
+		mac.Write(transcript)
+		return mac.Sum(nil)
+	}
+	tagA := tag(okm[:KEX_CONFIRMBYTES], "A")
+	tagB := tag(okm[KEX_CONFIRMBYTES:2*KEX_CONFIRMBYTES], "B")
+	ck := okm[2*KEX_CONFIRMBYTES:]
+	zeroBytes(okm[:2*KEX_CONFIRMBYTES])
+	return ck, tagA, tagB
+}
+
+// Kex_confirm_verify compares a received tag with the expected one in
+// constant time, returning ErrKexConfirmation if they differ
+func Kex_confirm_verify(tag []byte, expected []byte) error {
+	if subtle.ConstantTimeCompare(tag, expected) != 1 {
+		return ErrKexConfirmation
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package kyber
+
+import (
+	"bytes"
+	"testing"
+)
+
+func Test_KexConfirm(t *testing.T) {
+	kexpp := NewKexParameters(3)
+	pkb, skb := Crypto_kem_keypair(kexpp.KemParams)
+	pka, ska := Crypto_kem_keypair(kexpp.KemParams)
+	binding := &KexBinding{Context: []byte("confirm")}
+
+	sessions := map[string]func() (*Initiator, *Responder){
+		"uake": func() (*Initiator, *Responder) {
+			a, _ := NewUAKEInitiator(kexpp, pkb)
+			b, _ := NewUAKEResponder(kexpp, skb)
+			return a, b
+		},
+		"ake": func() (*Initiator, *Responder) {
+			a, _ := NewAKEInitiator(kexpp, pkb, ska)
+			b, _ := NewAKEResponder(kexpp, skb, pka)
+			return a, b
+		},
+		"bound ake": func() (*Initiator, *Responder) {
+			a, _ := NewBoundAKEInitiator(kexpp, pkb, ska, binding)
+			b, _ := NewBoundAKEResponder(kexpp, skb, pka, binding)
+			return a, b
+		},
+	}
+	for name, newSession := range sessions {
+		a, b := newSession()
+		senda, _ := a.Start()
+		if _, err := b.Confirm(nil); err != ErrKexState {
+			t.Errorf("%s: Confirm before RespondConfirm: got %v", name, err)
+		}
+		sendb, tagB, err := b.RespondConfirm(senda)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tagA, cka, err := a.FinishConfirm(sendb, tagB)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		ckb, err := b.Confirm(tagA)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !bytes.Equal(cka, ckb) || len(cka) != KEX_SSBYTES {
+			t.Errorf("%s: confirmed keys differ", name)
+		}
+		if _, err := b.Confirm(tagA); err != ErrKexState {
+			t.Errorf("%s: second Confirm: got %v", name, err)
+		}
+
+		// a modified answer makes A decapsulate to a random key
+		a, b = newSession()
+		senda, _ = a.Start()
+		sendb, tagB, _ = b.RespondConfirm(senda)
+		sendb[0] ^= 1
+		if _, _, err := a.FinishConfirm(sendb, tagB); err != ErrKexConfirmation {
+			t.Errorf("%s: modified answer: got %v", name, err)
+		}
+
+		// a modified first message makes B decapsulate to a random key
+		a, b = newSession()
+		senda, _ = a.Start()
+		bad := append([]byte{}, senda...)
+		bad[len(bad)-1] ^= 1
+		sendb, tagB, _ = b.RespondConfirm(bad)
+		if _, _, err := a.FinishConfirm(sendb, tagB); err != ErrKexConfirmation {
+			t.Errorf("%s: modified first message: got %v", name, err)
+		}
+
+		a, b = newSession()
+		senda, _ = a.Start()
+		sendb, tagB, _ = b.RespondConfirm(senda)
+		tagA, _, _ = a.FinishConfirm(sendb, tagB)
+		tagA[0] ^= 1
+		if _, err := b.Confirm(tagA); err != ErrKexConfirmation {
+			t.Errorf("%s: modified tagA: got %v", name, err)
+		}
+	}
+
+	// byte API over the unconfirmed flow
+	senda, tk, eska := Kex_uake_initA(kexpp, pkb)
+	sendb, kb := Kex_uake_sharedB(kexpp, senda, skb)
+	ka := Kex_uake_sharedA(kexpp, sendb, tk, eska)
+	cka, tagAa, tagBa := Kex_confirm(ka, senda, sendb)
+	ckb, tagAb, tagBb := Kex_confirm(kb, senda, sendb)
+	if Kex_confirm_verify(tagBb, tagBa) != nil || Kex_confirm_verify(tagAa, tagAb) != nil || !bytes.Equal(cka, ckb) {
+		t.Error("Kex_confirm disagrees between A and B")
+	}
+	if bytes.Equal(cka, ka) {
+		t.Error("confirmed key equals session key")
+	}
+	if _, _, tagB := Kex_confirm(kb, sendb, senda); Kex_confirm_verify(tagB, tagBa) == nil {
+		t.Error("swapped transcript not detected")
+	}
+}
@@ -105,3 +105,89 @@ func TestKex(t *testing.T) {
 	test_ake(4)
 
 }
+
+func TestKex_legacy_lengths(t *testing.T) {
+	params_kex := NewKexParameters(3)
+	pkb, skb := Crypto_kem_keypair(params_kex.KemParams)
+	pka, ska := Crypto_kem_keypair(params_kex.KemParams)
+
+	// Trailing bytes are ignored, short messages do not panic
+	senda, tk, eska := Kex_uake_initA(params_kex, pkb)
+	sendb, kb := Kex_uake_sharedB(params_kex, append(senda, 0xff), skb)
+	if ka := Kex_uake_sharedA(params_kex, sendb, tk, eska); !bytes.Equal(ka, kb) {
+		t.Error("UAKE keys differ for over-long message")
+	}
+	if sendb, kb := Kex_uake_sharedB(params_kex, senda[:10], skb); sendb != nil || kb != nil {
+		t.Error("Kex_uake_sharedB accepted a short message")
+	}
+
+	senda, tk, eska = Kex_ake_initA(params_kex, pkb)
+	sendb, kb = Kex_ake_sharedB(params_kex, append(senda, 0xff), skb, pka)
+	if ka := Kex_ake_sharedA(params_kex, sendb, tk, eska, ska); !bytes.Equal(ka, kb) {
+		t.Error("AKE keys differ for over-long message")
+	}
+	if sendb, kb := Kex_ake_sharedB(params_kex, senda[:10], skb, pka); sendb != nil || kb != nil {
+		t.Error("Kex_ake_sharedB accepted a short message")
+	}
+
+	// Trailing bytes of the public keys are ignored, short keys do not panic
+	senda, tk, eska = Kex_ake_initA(params_kex, append(pkb, 0xff))
+	sendb, kb = Kex_ake_sharedB(params_kex, senda, skb, append(pka, 0xff))
+	if ka := Kex_ake_sharedA(params_kex, sendb, tk, eska, ska); !bytes.Equal(ka, kb) {
+		t.Error("AKE keys differ for over-long public keys")
+	}
+	senda, tk, eska = Kex_uake_initA(params_kex, append(pkb, 0xff))
+	sendb, kb = Kex_uake_sharedB(params_kex, senda, skb)
+	if ka := Kex_uake_sharedA(params_kex, sendb, tk, eska); !bytes.Equal(ka, kb) {
+		t.Error("UAKE keys differ for over-long public key")
+	}
+	if senda, tk, eska := Kex_uake_initA(params_kex, pkb[:10]); senda != nil || tk != nil || eska != nil {
+		t.Error("Kex_uake_initA accepted a short public key")
+	}
+	if senda, tk, eska := Kex_ake_initA(params_kex, pkb[:10]); senda != nil || tk != nil || eska != nil {
+		t.Error("Kex_ake_initA accepted a short public key")
+	}
+	if sendb, kb := Kex_ake_sharedB(params_kex, senda, skb, pka[:10]); sendb != nil || kb != nil {
+		t.Error("Kex_ake_sharedB accepted a short public key")
+	}
+}
+
+func TestKex_rand(t *testing.T) {
+	params_kex := NewKexParameters(3)
+	pkb, skb := Crypto_kem_keypair(params_kex.KemParams)
+	pka, ska := Crypto_kem_keypair(params_kex.KemParams)
+
+	if _, _, _, err := Kex_uake_initA_rand(params_kex, pkb, failingReader{}); err == nil {
+		t.Error("Kex_uake_initA_rand ignored RNG failure")
+	}
+	if _, _, _, err := Kex_ake_initA_rand(params_kex, pkb, failingReader{}); err == nil {
+		t.Error("Kex_ake_initA_rand ignored RNG failure")
+	}
+
+	senda, tk, eska, err := Kex_ake_initA_rand(params_kex, pkb, newTestRand("alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Kex_ake_sharedB_rand(params_kex, senda, skb, pka, failingReader{}); err == nil {
+		t.Error("Kex_ake_sharedB_rand ignored RNG failure")
+	}
+	if _, _, err := Kex_ake_sharedB_rand(params_kex, senda[:10], skb, pka, newTestRand("bob")); err != ErrInvalidKexMessageLength {
+		t.Errorf("Kex_ake_sharedB_rand short message: got %v", err)
+	}
+	sendb, kb, err := Kex_ake_sharedB_rand(params_kex, senda, skb, pka, newTestRand("bob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ka := Kex_ake_sharedA(params_kex, sendb, tk, eska, ska); !bytes.Equal(ka, kb) {
+		t.Error("AKE keys differ")
+	}
+
+	senda, tk, eska, _ = Kex_uake_initA_rand(params_kex, pkb, newTestRand("alice"))
+	if _, _, err := Kex_uake_sharedB_rand(params_kex, senda, skb, failingReader{}); err == nil {
+		t.Error("Kex_uake_sharedB_rand ignored RNG failure")
+	}
+	sendb, kb, _ = Kex_uake_sharedB_rand(params_kex, senda, skb, newTestRand("bob"))
+	if ka := Kex_uake_sharedA(params_kex, sendb, tk, eska); !bytes.Equal(ka, kb) {
+		t.Error("UAKE keys differ")
+	}
+}
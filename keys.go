@@ -0,0 +1,381 @@
+package kyber
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/subtle"
+	"io"
+)
+
+// PublicKey is a KEM public key bound to its parameter set
+type PublicKey struct {
+	params *Parameters
+	pk     []byte // KYBER_PUBLICKEYBYTES
+}
+
+// PrivateKey is a KEM private key bound to its parameter set
+type PrivateKey struct {
+	params *Parameters
+	sk     []byte // KYBER_SECRETKEYBYTES
+	seed   []byte // KYBER_SEEDBYTES, nil if unknown
+}
+
+// Ciphertext is a KEM cipher text bound to its parameter set
+type Ciphertext struct {
+	params *Parameters
+	ct     []byte // KYBER_CIPHERTEXTBYTES
+}
+
+/*************************************************
+* Name:        GenerateKey
+*
+* Description: Generates a fresh private key
+*
+* Arguments:   - params *Parameters: Kem parameters struct
+*
+* Returns      - key *PrivateKey: output private key
+*              - err error: non-nil on failure
+**************************************************/
+func GenerateKey(params *Parameters) (*PrivateKey, error) {
+	return GenerateKeyRand(params, rand.Reader)
+}
+
+/*************************************************
+* Name:        GenerateKeyRand
+*
+* Description: Generates a fresh private key,
+*              drawing all randomness from rand
+*
+* Arguments:   - params *Parameters: Kem parameters struct
+*              - rand io.Reader: source of randomness
+*
+* Returns      - key *PrivateKey: output private key
+*              - err error: non-nil if rand fails
+**************************************************/
+func GenerateKeyRand(params *Parameters, rand io.Reader) (*PrivateKey, error) {
+	seed, err := randombytes_from(rand, KYBER_SEEDBYTES)
+	if err != nil {
+		return nil, err
+	}
+	return NewPrivateKeyFromSeed(params, seed)
+}
+
+/*************************************************
+* Name:        NewPrivateKeyFromSeed
+*
+* Description: Deterministically regenerates a private key
+*              from a seed d||z
+*
+* Arguments:   - params *Parameters: Kem parameters struct
+*              - seed []byte: input seed
+*                (of length KYBER_SEEDBYTES bytes)
+*
+* Returns      - key *PrivateKey: output private key
+*              - err error: ErrInvalidSeedLength on bad input
+**************************************************/
+func NewPrivateKeyFromSeed(params *Parameters, seed []byte) (*PrivateKey, error) {
+	_, sk, err := Crypto_kem_keypair_from_seed(params, seed)
+	if err != nil {
+		return nil, err
+	}
+	s := make([]byte, KYBER_SEEDBYTES)
+	copy(s, seed)
+	return &PrivateKey{params: params, sk: sk, seed: s}, nil
+}
+
+/*************************************************
+* Name:        ExpandSeed
+*
+* Description: Converts a private key from the compact seed
+*              form to the expanded form
+*
+* Arguments:   - params *Parameters: Kem parameters struct
+*              - seed []byte: input seed
+*                (of length KYBER_SEEDBYTES bytes)
+*
+* Returns      - sk []byte: output expanded private key
+*                (KYBER_SECRETKEYBYTES bytes)
+*              - err error: ErrInvalidSeedLength on bad input
+**************************************************/
+func ExpandSeed(params *Parameters, seed []byte) ([]byte, error) {
+	_, sk, err := Crypto_kem_keypair_from_seed(params, seed)
+	return sk, err
+}
+
+/*************************************************
+* Name:        ParsePrivateKeyWithSeed
+*
+* Description: Parses an expanded private key together with
+*              its known seed, verifying that they match
+*
+* Arguments:   - params *Parameters: Kem parameters struct
+*              - b []byte: input expanded private key
+*                (of length KYBER_SECRETKEYBYTES bytes)
+*              - seed []byte: input seed
+*                (of length KYBER_SEEDBYTES bytes)
+*
+* Returns      - key *PrivateKey: output private key
+*              - err error: ErrSeedMismatch if the seed does not
+*                expand to b, non-nil on other invalid input
+**************************************************/
+func ParsePrivateKeyWithSeed(params *Parameters, b []byte, seed []byte) (*PrivateKey, error) {
+	if len(b) != params.KYBER_SECRETKEYBYTES {
+		return nil, ErrInvalidSecretKeyLength
+	}
+	priv, err := NewPrivateKeyFromSeed(params, seed)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(priv.sk, b) != 1 {
+		return nil, ErrSeedMismatch
+	}
+	return priv, nil
+}
+
+/*************************************************
+* Name:        ParsePublicKey
+*
+* Description: Parses a serialized public key, applying the
+*              checks of crypto_kem_check_pk
+*
+* Arguments:   - params *Parameters: Kem parameters struct
+*              - b []byte: input public key
+*                (of length KYBER_PUBLICKEYBYTES bytes)
+*
+* Returns      - key *PublicKey: output public key
+*              - err error: non-nil on invalid input
+**************************************************/
+func ParsePublicKey(params *Parameters, b []byte) (*PublicKey, error) {
+	if err := Crypto_kem_check_pk(params, b); err != nil {
+		return nil, err
+	}
+	pk := make([]byte, params.KYBER_PUBLICKEYBYTES)
+	copy(pk, b)
+	return &PublicKey{params: params, pk: pk}, nil
+}
+
+/*************************************************
+* Name:        ParsePrivateKey
+*
+* Description: Parses a serialized private key, applying the
+*              checks of crypto_kem_check_sk
+*
+* Arguments:   - params *Parameters: Kem parameters struct
+*              - b []byte: input private key
+*                (of length KYBER_SECRETKEYBYTES bytes)
+*
+* Returns      - key *PrivateKey: output private key
+*              - err error: non-nil on invalid input
+**************************************************/
+func ParsePrivateKey(params *Parameters, b []byte) (*PrivateKey, error) {
+	if err := Crypto_kem_check_sk(params, b); err != nil {
+		return nil, err
+	}
+	sk := make([]byte, params.KYBER_SECRETKEYBYTES)
+	copy(sk, b)
+	return &PrivateKey{params: params, sk: sk}, nil
+}
+
+// Parameters returns the parameter set of the key
+func (pub *PublicKey) Parameters() *Parameters {
+	return pub.params
+}
+
+// Bytes returns a copy of the serialized public key
+func (pub *PublicKey) Bytes() []byte {
+	b := make([]byte, len(pub.pk))
+	copy(b, pub.pk)
+	return b
+}
+
+/*************************************************
+* Name:        (*PublicKey).Encapsulate
+*
+* Description: Generates cipher text and shared secret
+*              for the public key
+*
+* Returns      - ct []byte: output cipher text
+*                (KYBER_CIPHERTEXTBYTES bytes)
+*              - ss []byte: output shared secret
+*                (KYBER_SSBYTES bytes)
+*              - err error: non-nil on failure
+**************************************************/
+func (pub *PublicKey) Encapsulate() ([]byte, []byte, error) {
+	return pub.EncapsulateRand(rand.Reader)
+}
+
+// EncapsulateRand is Encapsulate drawing its randomness from rand
+func (pub *PublicKey) EncapsulateRand(rand io.Reader) ([]byte, []byte, error) {
+	return Crypto_kem_enc_rand(pub.params, pub.pk, rand)
+}
+
+/*************************************************
+* Name:        (*PublicKey).EncapsulateCiphertext
+*
+* Description: Generates cipher text and shared secret for the
+*              public key, returning the cipher text bound to the
+*              parameter set of the key
+*
+* Returns      - ct *Ciphertext: output cipher text
+*              - ss []byte: output shared secret
+*                (KYBER_SSBYTES bytes)
+*              - err error: non-nil on failure
+**************************************************/
+func (pub *PublicKey) EncapsulateCiphertext() (*Ciphertext, []byte, error) {
+	return pub.EncapsulateCiphertextRand(rand.Reader)
+}
+
+// EncapsulateCiphertextRand is EncapsulateCiphertext drawing its randomness from rand
+func (pub *PublicKey) EncapsulateCiphertextRand(rand io.Reader) (*Ciphertext, []byte, error) {
+	ct, ss, err := pub.EncapsulateRand(rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Ciphertext{params: pub.params, ct: ct}, ss, nil
+}
+
+// Equal reports whether x is a *PublicKey of the same parameter set and value
+func (pub *PublicKey) Equal(x crypto.PublicKey) bool {
+	xx, ok := x.(*PublicKey)
+	if !ok {
+		return false
+	}
+	return sameParameters(pub.params, xx.params) && subtle.ConstantTimeCompare(pub.pk, xx.pk) == 1
+}
+
+// Parameters returns the parameter set of the key
+func (priv *PrivateKey) Parameters() *Parameters {
+	return priv.params
+}
+
+// Bytes returns a copy of the serialized private key
+func (priv *PrivateKey) Bytes() []byte {
+	b := make([]byte, len(priv.sk))
+	copy(b, priv.sk)
+	return b
+}
+
+/*************************************************
+* Name:        (*PrivateKey).Seed
+*
+* Description: Returns the compact seed form d||z of the private key,
+*              if known. Keys generated by this package or loaded
+*              from a seed know it, keys parsed from the expanded
+*              form do not
+*
+* Returns      - seed []byte: output seed
+*                (KYBER_SEEDBYTES bytes)
+*              - err error: ErrSeedUnavailable if the seed is unknown
+**************************************************/
+func (priv *PrivateKey) Seed() ([]byte, error) {
+	if priv.seed == nil {
+		return nil, ErrSeedUnavailable
+	}
+	seed := make([]byte, KYBER_SEEDBYTES)
+	copy(seed, priv.seed)
+	return seed, nil
+}
+
+// PublicKey returns the public key embedded in the private key
+func (priv *PrivateKey) PublicKey() *PublicKey {
+	pk := make([]byte, priv.params.KYBER_PUBLICKEYBYTES)
+	copy(pk, priv.sk[priv.params.KYBER_INDCPA_SECRETKEYBYTES:])
+	return &PublicKey{params: priv.params, pk: pk}
+}
+
+// Public returns the public key as a crypto.PublicKey
+func (priv *PrivateKey) Public() crypto.PublicKey {
+	return priv.PublicKey()
+}
+
+/*************************************************
+* Name:        (*PrivateKey).Decapsulate
+*
+* Description: Generates shared secret for given cipher text
+*
+* Arguments:   - ct []byte: input cipher text
+*                (of length KYBER_CIPHERTEXTBYTES bytes)
+*
+* Returns      - ss []byte: output shared secret
+*                (KYBER_SSBYTES bytes)
+*              - err error: ErrInvalidCiphertextLength if ct does
+*                not belong to the parameter set of the key
+* On decapsulation failure, ss will contain a pseudo-random value.
+**************************************************/
+func (priv *PrivateKey) Decapsulate(ct []byte) ([]byte, error) {
+	return Crypto_kem_dec_checked(priv.params, ct, priv.sk)
+}
+
+/*************************************************
+* Name:        (*PrivateKey).DecapsulateCiphertext
+*
+* Description: Generates shared secret for a typed cipher text,
+*              rejecting cipher texts of another parameter set
+*              even if they have the same length
+*
+* Arguments:   - ct *Ciphertext: input cipher text
+*
+* Returns:     - ss []byte: output shared secret
+*                (KYBER_SSBYTES bytes)
+*              - err error: ErrCiphertextParameters if ct does not
+*                belong to the parameter set of the key
+* On decapsulation failure, ss will contain a pseudo-random value.
+**************************************************/
+func (priv *PrivateKey) DecapsulateCiphertext(ct *Ciphertext) ([]byte, error) {
+	if !sameParameters(priv.params, ct.params) {
+		return nil, ErrCiphertextParameters
+	}
+	return Crypto_kem_dec_checked(priv.params, ct.ct, priv.sk)
+}
+
+// Equal reports whether x is a *PrivateKey of the same parameter set and value
+func (priv *PrivateKey) Equal(x crypto.PrivateKey) bool {
+	xx, ok := x.(*PrivateKey)
+	if !ok {
+		return false
+	}
+	return sameParameters(priv.params, xx.params) && subtle.ConstantTimeCompare(priv.sk, xx.sk) == 1
+}
+
+/*************************************************
+* Name:        ParseCiphertext
+*
+* Description: Parses a serialized cipher text of the
+*              given parameter set
+*
+* Arguments:   - params *Parameters: Kem parameters struct
+*              - b []byte: input cipher text
+*                (of length KYBER_CIPHERTEXTBYTES bytes)
+*
+* Returns      - ct *Ciphertext: output cipher text
+*              - err error: ErrInvalidCiphertextLength on bad input
+**************************************************/
+func ParseCiphertext(params *Parameters, b []byte) (*Ciphertext, error) {
+	if len(b) != params.KYBER_CIPHERTEXTBYTES {
+		return nil, ErrInvalidCiphertextLength
+	}
+	ct := make([]byte, params.KYBER_CIPHERTEXTBYTES)
+	copy(ct, b)
+	return &Ciphertext{params: params, ct: ct}, nil
+}
+
+// Parameters returns the parameter set of the cipher text
+func (ct *Ciphertext) Parameters() *Parameters {
+	return ct.params
+}
+
+// Bytes returns a copy of the serialized cipher text
+func (ct *Ciphertext) Bytes() []byte {
+	b := make([]byte, len(ct.ct))
+	copy(b, ct.ct)
+	return b
+}
+
+// Equal reports whether x is a cipher text of the same parameter set and value
+func (ct *Ciphertext) Equal(x *Ciphertext) bool {
+	return sameParameters(ct.params, x.params) && subtle.ConstantTimeCompare(ct.ct, x.ct) == 1
+}
+
+func sameParameters(a *Parameters, b *Parameters) bool {
+	return a.KYBER_K == b.KYBER_K && a.KYBER_MODE == b.KYBER_MODE
+}
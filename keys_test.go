@@ -0,0 +1,167 @@
+package kyber
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func Test_Keys(t *testing.T) {
+	for _, params := range []*Parameters{NewParameters(2), NewParameters(3), NewMLKEMParameters(4)} {
+		priv, err := GenerateKey(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub := priv.PublicKey()
+
+		ct, ss, err := pub.Encapsulate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ss2, err := priv.Decapsulate(ct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ss, ss2) {
+			t.Errorf("%s: shared secrets differ", params.KYBER_NAME)
+		}
+
+		pub2, err := ParsePublicKey(params, pub.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pub.Equal(pub2) || !pub2.Equal(priv.Public()) {
+			t.Errorf("%s: parsed public key not equal", params.KYBER_NAME)
+		}
+
+		priv2, err := ParsePrivateKey(params, priv.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !priv.Equal(priv2) {
+			t.Errorf("%s: parsed private key not equal", params.KYBER_NAME)
+		}
+
+		other, _ := GenerateKey(params)
+		if pub.Equal(other.PublicKey()) || priv.Equal(other) {
+			t.Errorf("%s: distinct keys compare equal", params.KYBER_NAME)
+		}
+	}
+}
+
+func Test_Keys_mismatched_parameters(t *testing.T) {
+	params_512 := NewParameters(2)
+	params_768 := NewParameters(3)
+
+	priv, _ := GenerateKey(params_512)
+	pub768, _ := GenerateKey(params_768)
+	ct, _, _ := pub768.PublicKey().Encapsulate()
+
+	if _, err := priv.Decapsulate(ct); !errors.Is(err, ErrInvalidCiphertextLength) {
+		t.Errorf("Kyber768 ciphertext with Kyber512 key: got %v", err)
+	}
+	if _, err := ParsePublicKey(params_768, priv.PublicKey().Bytes()); !errors.Is(err, ErrInvalidPublicKeyLength) {
+		t.Errorf("Kyber512 public key as Kyber768: got %v", err)
+	}
+
+	// Same sizes, different mode
+	mlkem_512 := NewMLKEMParameters(2)
+	pub, _ := ParsePublicKey(mlkem_512, priv.PublicKey().Bytes())
+	if pub.Equal(priv.PublicKey()) {
+		t.Errorf("Kyber512 and ML-KEM-512 keys compare equal")
+	}
+}
+
+func Test_Keys_ciphertext(t *testing.T) {
+	params := NewParameters(2)
+	priv, _ := GenerateKey(params)
+
+	ct, ss, err := priv.PublicKey().EncapsulateCiphertext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss2, err := priv.DecapsulateCiphertext(ct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ss, ss2) {
+		t.Errorf("shared secrets differ")
+	}
+
+	ct2, err := ParseCiphertext(params, ct.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ct.Equal(ct2) {
+		t.Errorf("parsed ciphertext not equal")
+	}
+	if _, err := ParseCiphertext(NewParameters(3), ct.Bytes()); !errors.Is(err, ErrInvalidCiphertextLength) {
+		t.Errorf("Kyber512 ciphertext as Kyber768: got %v", err)
+	}
+
+	// Same length, different mode
+	mlkem_512 := NewMLKEMParameters(2)
+	ct3, err := ParseCiphertext(mlkem_512, ct.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct.Equal(ct3) {
+		t.Errorf("Kyber512 and ML-KEM-512 ciphertexts compare equal")
+	}
+	if _, err := priv.DecapsulateCiphertext(ct3); !errors.Is(err, ErrCiphertextParameters) {
+		t.Errorf("ML-KEM-512 ciphertext with Kyber512 key: got %v", err)
+	}
+}
+
+func Test_Keys_seed(t *testing.T) {
+	params := NewMLKEMParameters(4)
+
+	priv, err := GenerateKey(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed, err := priv.Seed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seed) != KYBER_SEEDBYTES {
+		t.Fatalf("seed length %d", len(seed))
+	}
+
+	priv2, err := NewPrivateKeyFromSeed(params, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !priv.Equal(priv2) {
+		t.Error("private key loaded from seed differs")
+	}
+
+	sk, err := ExpandSeed(params, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sk, priv.Bytes()) {
+		t.Error("ExpandSeed differs from private key")
+	}
+
+	expanded, err := ParsePrivateKey(params, sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := expanded.Seed(); !errors.Is(err, ErrSeedUnavailable) {
+		t.Errorf("seed of expanded key: got %v", err)
+	}
+
+	withSeed, err := ParsePrivateKeyWithSeed(params, sk, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := withSeed.Seed(); !bytes.Equal(s, seed) {
+		t.Error("ParsePrivateKeyWithSeed lost the seed")
+	}
+
+	other, _ := GenerateKey(params)
+	if _, err := ParsePrivateKeyWithSeed(params, other.Bytes(), seed); !errors.Is(err, ErrSeedMismatch) {
+		t.Errorf("mismatched seed: got %v", err)
+	}
+}
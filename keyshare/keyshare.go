@@ -0,0 +1,227 @@
+// Package keyshare produces and consumes the TLS 1.3 key_share
+// key_exchange fields of the hybrid groups
+//
+//   - X25519Kyber768Draft00 (0x6399, draft-tls-westerbaan-xyber768d00):
+//     X25519 first, round-3 Kyber768
+//   - X25519MLKEM768 (0x11EC, draft-kwiatkowski-tls-ecdhe-mlkem):
+//     ML-KEM-768 first
+//
+// In both, the client share is a public key pair, the server share
+// an ephemeral X25519 public key and a KEM ciphertext, and the shared
+// secret the concatenation of both shared secrets in share order.
+package keyshare
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	kyber "github.com/depressi0n/kyber-go"
+)
+
+// TLS NamedGroup codepoints
+const (
+	X25519Kyber768Draft00 uint16 = 0x6399
+	X25519MLKEM768        uint16 = 0x11EC
+)
+
+const (
+	x25519Size = 32
+
+	ClientShareSize  = 1184 + x25519Size
+	ServerShareSize  = 1088 + x25519Size
+	SharedSecretSize = kyber.KYBER_SSBYTES + x25519Size
+)
+
+var (
+	ErrInvalidClientShare = errors.New("keyshare: invalid client key share")
+	ErrInvalidServerShare = errors.New("keyshare: invalid server key share")
+)
+
+type group struct {
+	params      *kyber.Parameters
+	x25519First bool
+}
+
+var (
+	kyber768Draft00 = &group{kyber.NewParameters(3), true}
+	mlkem768        = &group{kyber.NewMLKEMParameters(3), false}
+)
+
+func groupByID(id uint16) (*group, error) {
+	switch id {
+	case X25519Kyber768Draft00:
+		return kyber768Draft00, nil
+	case X25519MLKEM768:
+		return mlkem768, nil
+	}
+	return nil, fmt.Errorf("keyshare: unsupported group %#04x", id)
+}
+
+// split returns the X25519 and KEM parts of a share
+func (g *group) split(share []byte) ([]byte, []byte) {
+	if g.x25519First {
+		return share[:x25519Size], share[x25519Size:]
+	}
+	return share[len(share)-x25519Size:], share[:len(share)-x25519Size]
+}
+
+// join orders the X25519 and KEM parts of a share or shared secret
+func (g *group) join(x, k []byte) []byte {
+	out := make([]byte, 0, len(x)+len(k))
+	if g.x25519First {
+		return append(append(out, x...), k...)
+	}
+	return append(append(out, k...), x...)
+}
+
+// ClientKeyShare holds the client secrets of a hybrid key share
+type ClientKeyShare struct {
+	group *group
+	id    uint16
+	x     *ecdh.PrivateKey
+	sk    []byte // KYBER_SECRETKEYBYTES
+	share []byte
+}
+
+/*************************************************
+* Name:        NewClientKeyShare
+*
+* Description: Generates the client key pairs for group
+*
+* Arguments:   - group uint16: NamedGroup codepoint
+*              - rand io.Reader: source of randomness,
+*                or nil for crypto/rand
+*
+* Returns      - c *ClientKeyShare: output client key share
+*              - err error: non-nil on failure
+**************************************************/
+func NewClientKeyShare(group uint16, r io.Reader) (*ClientKeyShare, error) {
+	g, err := groupByID(group)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		r = rand.Reader
+	}
+	pk, sk, err := kyber.Crypto_kem_keypair_rand(g.params, r)
+	if err != nil {
+		return nil, err
+	}
+	seed := make([]byte, x25519Size)
+	if _, err := io.ReadFull(r, seed); err != nil {
+		return nil, err
+	}
+	x, err := ecdh.X25519().NewPrivateKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientKeyShare{
+		group: g,
+		id:    group,
+		x:     x,
+		sk:    sk,
+		share: g.join(x.PublicKey().Bytes(), pk),
+	}, nil
+}
+
+// Group returns the NamedGroup codepoint of the share
+func (c *ClientKeyShare) Group() uint16 { return c.id }
+
+// Bytes returns the key_exchange field of the client KeyShareEntry
+func (c *ClientKeyShare) Bytes() []byte {
+	return append([]byte{}, c.share...)
+}
+
+/*************************************************
+* Name:        (*ClientKeyShare).SharedSecret
+*
+* Description: Computes the shared secret from the key_exchange
+*              field of the server KeyShareEntry
+*
+* Arguments:   - serverShare []byte: input server share
+*                (of length ServerShareSize bytes)
+*
+* Returns      - ss []byte: output shared secret
+*                (SharedSecretSize bytes)
+*              - err error: non-nil on invalid input
+**************************************************/
+func (c *ClientKeyShare) SharedSecret(serverShare []byte) ([]byte, error) {
+	if len(serverShare) != ServerShareSize {
+		return nil, ErrInvalidServerShare
+	}
+	xs, ct := c.group.split(serverShare)
+	peer, err := ecdh.X25519().NewPublicKey(xs)
+	if err != nil {
+		return nil, ErrInvalidServerShare
+	}
+	ssX, err := c.x.ECDH(peer)
+	if err != nil {
+		return nil, err
+	}
+	ssK, err := kyber.Crypto_kem_dec_checked(c.group.params, ct, c.sk)
+	if err != nil {
+		return nil, err
+	}
+	return c.group.join(ssX, ssK), nil
+}
+
+/*************************************************
+* Name:        ServerKeyShare
+*
+* Description: Answers a client key share: encapsulates to its
+*              KEM public key and runs X25519 against it
+*
+* Arguments:   - group uint16: NamedGroup codepoint
+*              - clientShare []byte: input client share
+*                (of length ClientShareSize bytes)
+*              - rand io.Reader: source of randomness,
+*                or nil for crypto/rand
+*
+* Returns      - serverShare []byte: output server share
+*                (ServerShareSize bytes)
+*              - ss []byte: output shared secret
+*                (SharedSecretSize bytes)
+*              - err error: non-nil on invalid input
+**************************************************/
+func ServerKeyShare(group uint16, clientShare []byte, r io.Reader) ([]byte, []byte, error) {
+	g, err := groupByID(group)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(clientShare) != ClientShareSize {
+		return nil, nil, ErrInvalidClientShare
+	}
+	if r == nil {
+		r = rand.Reader
+	}
+	xc, pk := g.split(clientShare)
+	// the encapsulation key check of FIPS 203 applies to both groups
+	if err := kyber.Crypto_kem_check_pk(g.params, pk); err != nil {
+		return nil, nil, ErrInvalidClientShare
+	}
+	peer, err := ecdh.X25519().NewPublicKey(xc)
+	if err != nil {
+		return nil, nil, ErrInvalidClientShare
+	}
+
+	ct, ssK, err := kyber.Crypto_kem_enc_rand(g.params, pk, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	seed := make([]byte, x25519Size)
+	if _, err := io.ReadFull(r, seed); err != nil {
+		return nil, nil, err
+	}
+	x, err := ecdh.X25519().NewPrivateKey(seed)
+	if err != nil {
+		return nil, nil, err
+	}
+	ssX, err := x.ECDH(peer)
+	if err != nil {
+		return nil, nil, err
+	}
+	return g.join(x.PublicKey().Bytes(), ct), g.join(ssX, ssK), nil
+}
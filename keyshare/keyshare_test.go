@@ -0,0 +1,196 @@
+package keyshare
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+
+	"golang.org/x/crypto/sha3"
+)
+
+func newTestRand(seed string) io.Reader {
+	h := sha3.NewShake128()
+	h.Write([]byte(seed))
+	return h
+}
+
+func TestKeyShare(t *testing.T) {
+	for _, group := range []uint16{X25519Kyber768Draft00, X25519MLKEM768} {
+		c, err := NewClientKeyShare(group, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Group() != group || len(c.Bytes()) != ClientShareSize {
+			t.Fatalf("%#04x: bad client share", group)
+		}
+		serverShare, ss, err := ServerKeyShare(group, c.Bytes(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(serverShare) != ServerShareSize || len(ss) != SharedSecretSize {
+			t.Fatalf("%#04x: got %d byte server share, %d byte secret", group, len(serverShare), len(ss))
+		}
+		ss2, err := c.SharedSecret(serverShare)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ss, ss2) {
+			t.Errorf("%#04x: shared secrets differ", group)
+		}
+
+		if _, _, err := ServerKeyShare(group, c.Bytes()[1:], nil); err != ErrInvalidClientShare {
+			t.Errorf("%#04x: short client share: got %v", group, err)
+		}
+		if _, err := c.SharedSecret(serverShare[1:]); err != ErrInvalidServerShare {
+			t.Errorf("%#04x: short server share: got %v", group, err)
+		}
+	}
+	if _, err := NewClientKeyShare(0x001d, nil); err == nil {
+		t.Error("NewClientKeyShare accepted X25519 alone")
+	}
+}
+
+func TestKeyShareLayout(t *testing.T) {
+	// a 0x6399 share reordered into 0x11EC layout: the X25519 halves
+	// agree, round-3 Kyber and ML-KEM do not
+	c, err := NewClientKeyShare(X25519Kyber768Draft00, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	share := c.Bytes()
+	swapped := append(append([]byte{}, share[32:]...), share[:32]...)
+	serverShare, ss, err := ServerKeyShare(X25519MLKEM768, swapped, nil)
+	if err != nil {
+		t.Fatalf("reordered share rejected: %v", err)
+	}
+	ss2, err := c.SharedSecret(append(append([]byte{}, serverShare[1088:]...), serverShare[:1088]...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(ss[:32], ss2[32:]) {
+		t.Error("round-3 Kyber and ML-KEM agreed on a shared secret")
+	}
+	if !bytes.Equal(ss[32:], ss2[:32]) {
+		t.Error("X25519 shared secrets differ")
+	}
+}
+
+// TestDraft00Transcript checks 0x6399 against key_exchange fields produced
+// by the circl implementation, see testdata/x25519kyber768draft00.json
+func TestDraft00Transcript(t *testing.T) {
+	data, err := os.ReadFile("testdata/x25519kyber768draft00.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v map[string]string
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+	h := func(name string) []byte {
+		b, err := hex.DecodeString(v[name])
+		if err != nil || len(b) == 0 {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return b
+	}
+	clientShare, serverShare, ss := h("client_share"), h("server_share"), h("shared_secret")
+
+	// X25519 comes first in every field
+	xc, err := ecdh.X25519().NewPrivateKey(h("client_x25519_private"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	xs, err := ecdh.X25519().NewPrivateKey(h("server_x25519_private"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ssX, err := xc.ECDH(xs.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(clientShare[:x25519Size], xc.PublicKey().Bytes()) ||
+		!bytes.Equal(serverShare[:x25519Size], xs.PublicKey().Bytes()) ||
+		!bytes.Equal(ss[:x25519Size], ssX) {
+		t.Fatal("X25519 parts are not first")
+	}
+	if len(clientShare) != ClientShareSize || len(serverShare) != ServerShareSize || len(ss) != SharedSecretSize {
+		t.Fatalf("got %d/%d/%d byte fields", len(clientShare), len(serverShare), len(ss))
+	}
+
+	c, err := NewClientKeyShare(X25519Kyber768Draft00,
+		bytes.NewReader(append(h("client_kyber768_seed"), h("client_x25519_private")...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(c.Bytes(), clientShare) {
+		t.Error("client share differs")
+	}
+	got, err := c.SharedSecret(serverShare)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, ss) {
+		t.Error("client shared secret differs")
+	}
+
+	gotShare, got, err := ServerKeyShare(X25519Kyber768Draft00, clientShare,
+		bytes.NewReader(append(h("server_kyber768_coins"), h("server_x25519_private")...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(gotShare, serverShare) {
+		t.Error("server share differs")
+	}
+	if !bytes.Equal(got, ss) {
+		t.Error("server shared secret differs")
+	}
+}
+
+// Regression vectors: SHA-256 of the client share, server share and
+// shared secret for a fixed randomness stream. The drafts publish no
+// known-answer tests; 0x6399 is checked against circl above and
+// 0x11EC against crypto/tls by tls_test.go
+func TestKeyShareVectors(t *testing.T) {
+	for _, v := range []struct {
+		group                  uint16
+		client, server, secret string
+	}{
+		{
+			X25519Kyber768Draft00,
+			"e508069803813b15a1e8eccb44fd24e3e3ae368fa70a358e6c70d1678b13bbfb",
+			"83ce2f42a5d654cc5961abb60be7fd285687ad3e53de33e9b564c2b9cc11eff0",
+			"dc9461f3fa920ec4b99c1db2ba49681579e87f0f79b4c2e2ddcac23c440b855e",
+		},
+		{
+			X25519MLKEM768,
+			"d5252eb229729b9f31fef2ee8029655c413fa074f349f2ec73a1c791f4432a32",
+			"e6cf2a2a1abab30a9a0be411d0f442a54fcc8aea141dd7ee9b322ee264ac7904",
+			"e22fb35735feb5b2960964033084287dd3343ef900354bfb576dc2c525726234",
+		},
+	} {
+		r := newTestRand("keyshare")
+		c, err := NewClientKeyShare(v.group, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		serverShare, ss, err := ServerKeyShare(v.group, c.Bytes(), r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, x := range []struct {
+			name string
+			data []byte
+			want string
+		}{{"client share", c.Bytes(), v.client}, {"server share", serverShare, v.server}, {"shared secret", ss, v.secret}} {
+			sum := sha256.Sum256(x.data)
+			if got := hex.EncodeToString(sum[:]); got != x.want {
+				t.Errorf("%#04x %s: got %s, want %s", v.group, x.name, got, x.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+//go:build go1.24
+
+package keyshare
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/mlkem"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	kyber "github.com/depressi0n/kyber-go"
+)
+
+// captureKeyShare returns the X25519MLKEM768 key_exchange field of a
+// ClientHello sent by crypto/tls
+func captureKeyShare(t *testing.T) []byte {
+	c1, c2 := net.Pipe()
+	defer c2.Close()
+	go func() {
+		tls.Client(c1, &tls.Config{
+			ServerName:       "example.com",
+			MinVersion:       tls.VersionTLS13,
+			CurvePreferences: []tls.CurveID{tls.X25519MLKEM768},
+		}).Handshake()
+		c1.Close()
+	}()
+
+	hdr := make([]byte, 5)
+	if _, err := io.ReadFull(c2, hdr); err != nil {
+		t.Fatal(err)
+	}
+	rec := make([]byte, binary.BigEndian.Uint16(hdr[3:]))
+	if _, err := io.ReadFull(c2, rec); err != nil {
+		t.Fatal(err)
+	}
+
+	// handshake header, legacy_version, random
+	b := rec[4+2+32:]
+	b = b[1+int(b[0]):]                          // legacy_session_id
+	b = b[2+int(binary.BigEndian.Uint16(b)):]    // cipher_suites
+	b = b[1+int(b[0]):]                          // legacy_compression_methods
+	b = b[2 : 2+int(binary.BigEndian.Uint16(b))] // extensions
+	for len(b) > 0 {
+		typ, n := binary.BigEndian.Uint16(b), int(binary.BigEndian.Uint16(b[2:]))
+		ext := b[4 : 4+n]
+		b = b[4+n:]
+		if typ != 51 { // key_share
+			continue
+		}
+		shares := ext[2:]
+		for len(shares) > 0 {
+			group, n := binary.BigEndian.Uint16(shares), int(binary.BigEndian.Uint16(shares[2:]))
+			if group == X25519MLKEM768 {
+				return shares[4 : 4+n]
+			}
+			shares = shares[4+n:]
+		}
+	}
+	t.Fatal("no X25519MLKEM768 key share in ClientHello")
+	return nil
+}
+
+func TestCryptoTLSClientShare(t *testing.T) {
+	share := captureKeyShare(t)
+	if len(share) != ClientShareSize {
+		t.Fatalf("crypto/tls client share is %d bytes", len(share))
+	}
+	// ML-KEM-768 encapsulation key first, X25519 last
+	if _, err := kyber.ParsePublicKey(kyber.NewMLKEMParameters(3), share[:1184]); err != nil {
+		t.Errorf("leading 1184 bytes are not an ML-KEM-768 key: %v", err)
+	}
+	if _, _, err := ServerKeyShare(X25519MLKEM768, share, nil); err != nil {
+		t.Errorf("ServerKeyShare rejected the crypto/tls share: %v", err)
+	}
+}
+
+func TestCryptoMLKEMPeer(t *testing.T) {
+	// our client against a server built on crypto/mlkem and crypto/ecdh
+	c, err := NewClientKeyShare(X25519MLKEM768, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	share := c.Bytes()
+	ek, err := mlkem.NewEncapsulationKey768(share[:1184])
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer, err := ecdh.X25519().NewPublicKey(share[1184:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ssK, ct := ek.Encapsulate()
+	x, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	ssX, _ := x.ECDH(peer)
+	ss, err := c.SharedSecret(append(ct, x.PublicKey().Bytes()...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ss, append(ssK, ssX...)) {
+		t.Error("client shared secret differs from crypto/mlkem server")
+	}
+
+	// our server against a client built on crypto/mlkem and crypto/ecdh
+	dk, _ := mlkem.GenerateKey768()
+	cx, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	serverShare, ss, err := ServerKeyShare(X25519MLKEM768, append(dk.EncapsulationKey().Bytes(), cx.PublicKey().Bytes()...), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ssK, err = dk.Decapsulate(serverShare[:1088])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sx, _ := ecdh.X25519().NewPublicKey(serverShare[1088:])
+	ssX, _ = cx.ECDH(sx)
+	if !bytes.Equal(ss, append(ssK, ssX...)) {
+		t.Error("server shared secret differs from crypto/mlkem client")
+	}
+}
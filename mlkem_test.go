@@ -0,0 +1,94 @@
+//go:build go1.26
+
+package kyber
+
+import (
+	"bytes"
+	"crypto/mlkem"
+	"crypto/mlkem/mlkemtest"
+	"testing"
+)
+
+// Cross-check the ML-KEM mode against the FIPS 203 implementation of the
+// Go standard library, which only provides ML-KEM-768 and ML-KEM-1024.
+func mlkem_compare_with_std(t *testing.T, params *Parameters) {
+	for i := 0; i < 100; i++ {
+		seed := randombytes(KYBER_SEEDBYTES)
+
+		pk, sk, err := Crypto_kem_keypair_from_seed(params, seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var ek interface{ Bytes() []byte }
+		var encapsulate func(m []byte) ([]byte, []byte, error)
+		var decapsulate func(ct []byte) ([]byte, error)
+		switch params.KYBER_K {
+		case 3:
+			dk, err := mlkem.NewDecapsulationKey768(seed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ek = dk.EncapsulationKey()
+			encapsulate = func(m []byte) ([]byte, []byte, error) {
+				return mlkemtest.Encapsulate768(dk.EncapsulationKey(), m)
+			}
+			decapsulate = dk.Decapsulate
+		case 4:
+			dk, err := mlkem.NewDecapsulationKey1024(seed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ek = dk.EncapsulationKey()
+			encapsulate = func(m []byte) ([]byte, []byte, error) {
+				return mlkemtest.Encapsulate1024(dk.EncapsulationKey(), m)
+			}
+			decapsulate = dk.Decapsulate
+		}
+
+		if !bytes.Equal(pk, ek.Bytes()) {
+			t.Fatalf("%s: public key differs from crypto/mlkem", params.KYBER_NAME)
+		}
+
+		m := randombytes(KYBER_SYMBYTES)
+		stdss, stdct, err := encapsulate(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ct, ss, err := EncapsulateDerand(params, pk, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ct, stdct) || !bytes.Equal(ss, stdss) {
+			t.Fatalf("%s: encapsulation differs from crypto/mlkem", params.KYBER_NAME)
+		}
+
+		if !bytes.Equal(Crypto_kem_dec(params, stdct, sk), stdss) {
+			t.Fatalf("%s: decapsulation of crypto/mlkem ciphertext failed", params.KYBER_NAME)
+		}
+
+		// Implicit rejection must derive the same J(z||c)
+		stdct[0] ^= 1
+		stdss, err = decapsulate(stdct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(Crypto_kem_dec(params, stdct, sk), stdss) {
+			t.Fatalf("%s: implicit rejection differs from crypto/mlkem", params.KYBER_NAME)
+		}
+
+		ct, ss = Crypto_kem_enc(params, pk)
+		stdss, err = decapsulate(ct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ss, stdss) {
+			t.Fatalf("%s: crypto/mlkem decapsulation differs", params.KYBER_NAME)
+		}
+	}
+}
+
+func Test_MLKEM_with_std(t *testing.T) {
+	mlkem_compare_with_std(t, NewMLKEMParameters(3))
+	mlkem_compare_with_std(t, NewMLKEMParameters(4))
+}
@@ -0,0 +1,416 @@
+package noise
+
+import (
+	"crypto/rand"
+	"io"
+	"strings"
+
+	kyber "github.com/depressi0n/kyber-go"
+)
+
+// Tokens of the PQNoise message patterns:
+//
+//	e     the sender's ephemeral KEM public key, MixHash'd
+//	s     the sender's static KEM public key, EncryptAndHash'd
+//	ekem  encapsulation to the peer's ephemeral key: the ciphertext
+//	      is MixHash'd and the shared secret MixKey'd
+//	skem  encapsulation to the peer's static key: the ciphertext
+//	      is EncryptAndHash'd and the shared secret MixKey'd
+const (
+	tokenE    = "e"
+	tokenS    = "s"
+	tokenEkem = "ekem"
+	tokenSkem = "skem"
+)
+
+// HandshakePattern is a PQNoise handshake pattern. Messages alternate
+// between initiator and responder, starting with the initiator.
+//
+// The patterns below are the KEM versions of NN, NK, IK and XX of
+// Schwabe, Stebila and Wiggers, "Post-Quantum Noise" (ACM CCS 2022,
+// ePrint 2022/539), written out token by token in the Noise notation.
+// Within a message the tokens run in the order given here, e before
+// skem; a peer only interoperates if it uses the same order. The
+// vectors in testdata are regression vectors of this package, not
+// interoperability vectors
+type HandshakePattern struct {
+	Name string
+	// responder static key known to the initiator beforehand
+	ResponderPreMessage bool
+	Messages            [][]string
+}
+
+var (
+	// HandshakePQNN has no static keys
+	//
+	//	-> e
+	//	<- ekem
+	HandshakePQNN = HandshakePattern{
+		Name: "pqNN",
+		Messages: [][]string{
+			{tokenE},
+			{tokenEkem},
+		},
+	}
+	// HandshakePQNK authenticates a responder the initiator knows
+	//
+	//	<- s
+	//	...
+	//	-> e, skem
+	//	<- ekem
+	HandshakePQNK = HandshakePattern{
+		Name:                "pqNK",
+		ResponderPreMessage: true,
+		Messages: [][]string{
+			{tokenE, tokenSkem},
+			{tokenEkem},
+		},
+	}
+	// HandshakePQIK authenticates both parties, sending the initiator
+	// static key in the first message to a known responder
+	//
+	//	<- s
+	//	...
+	//	-> e, skem, s
+	//	<- ekem, skem
+	HandshakePQIK = HandshakePattern{
+		Name:                "pqIK",
+		ResponderPreMessage: true,
+		Messages: [][]string{
+			{tokenE, tokenSkem, tokenS},
+			{tokenEkem, tokenSkem},
+		},
+	}
+	// HandshakePQXX authenticates both parties, exchanging static keys
+	//
+	//	-> e
+	//	<- ekem, s
+	//	-> skem, s
+	//	<- skem
+	HandshakePQXX = HandshakePattern{
+		Name: "pqXX",
+		Messages: [][]string{
+			{tokenE},
+			{tokenEkem, tokenS},
+			{tokenSkem, tokenS},
+			{tokenSkem},
+		},
+	}
+)
+
+// KeyPair is a KEM key pair
+type KeyPair struct {
+	Public  []byte // KYBER_PUBLICKEYBYTES
+	Private []byte // KYBER_SECRETKEYBYTES
+}
+
+// GenerateKeyPair generates a static KEM key pair, reading randomness
+// from rand, or crypto/rand if nil
+func GenerateKeyPair(params *kyber.Parameters, r io.Reader) (KeyPair, error) {
+	if r == nil {
+		r = rand.Reader
+	}
+	pk, sk, err := kyber.Crypto_kem_keypair_rand(params, r)
+	if err != nil {
+		return KeyPair{}, err
+	}
+	return KeyPair{Public: pk, Private: sk}, nil
+}
+
+// Config configures a HandshakeState
+type Config struct {
+	Params    *kyber.Parameters
+	Pattern   HandshakePattern
+	Initiator bool
+	Prologue  []byte
+	// StaticKeypair is the local static key pair, if the pattern sends one
+	StaticKeypair KeyPair
+	// PeerStatic is the remote static public key, if known beforehand
+	PeerStatic []byte
+	// Random is the source of randomness, crypto/rand if nil
+	Random io.Reader
+}
+
+// HandshakeState runs a handshake pattern for one party
+type HandshakeState struct {
+	ss        SymmetricState
+	params    *kyber.Parameters
+	s, e      KeyPair
+	rs, re    []byte
+	initiator bool
+	messages  [][]string
+	msgIdx    int
+	rand      io.Reader
+}
+
+// ProtocolName returns the Noise protocol name of a configuration,
+// e.g. Noise_pqXX_MLKEM768_ChaChaPoly_SHA256
+func ProtocolName(params *kyber.Parameters, pattern HandshakePattern) string {
+	return "Noise_" + pattern.Name + "_" + strings.ReplaceAll(params.KYBER_NAME, "-", "") + "_ChaChaPoly_SHA256"
+}
+
+/*************************************************
+* Name:        NewHandshakeState
+*
+* Description: Initializes a handshake: the symmetric state from the
+*              protocol name, then the prologue and pre-message keys
+*
+* Arguments:   - c Config: handshake configuration
+*
+* Returns      - hs *HandshakeState: output handshake state
+*              - err error: ErrMissingKey if the pattern needs a key
+*                the configuration lacks, non-nil on invalid keys
+**************************************************/
+func NewHandshakeState(c Config) (*HandshakeState, error) {
+	hs := &HandshakeState{
+		params:    c.Params,
+		s:         c.StaticKeypair,
+		rs:        c.PeerStatic,
+		initiator: c.Initiator,
+		messages:  c.Pattern.Messages,
+		rand:      c.Random,
+	}
+	if hs.rand == nil {
+		hs.rand = rand.Reader
+	}
+
+	sendsStatic, needsPeer := false, c.Pattern.ResponderPreMessage && c.Initiator
+	for i, m := range c.Pattern.Messages {
+		for _, token := range m {
+			if token == tokenS && (i%2 == 0) == c.Initiator {
+				sendsStatic = true
+			}
+		}
+	}
+	if c.Pattern.ResponderPreMessage && !c.Initiator {
+		sendsStatic = true
+	}
+	if sendsStatic {
+		if len(hs.s.Private) != c.Params.KYBER_SECRETKEYBYTES || len(hs.s.Public) != c.Params.KYBER_PUBLICKEYBYTES {
+			return nil, ErrMissingKey
+		}
+		if err := kyber.Crypto_kem_check_sk(c.Params, hs.s.Private); err != nil {
+			return nil, err
+		}
+	}
+	if needsPeer {
+		if err := kyber.Crypto_kem_check_pk(c.Params, hs.rs); err != nil {
+			return nil, ErrMissingKey
+		}
+	}
+
+	hs.ss.InitializeSymmetric([]byte(ProtocolName(c.Params, c.Pattern)))
+	hs.ss.MixHash(c.Prologue)
+	if c.Pattern.ResponderPreMessage {
+		if c.Initiator {
+			hs.ss.MixHash(hs.rs)
+		} else {
+			hs.ss.MixHash(hs.s.Public)
+		}
+	}
+	return hs, nil
+}
+
+func (hs *HandshakeState) myTurn() bool {
+	return (hs.msgIdx%2 == 0) == hs.initiator
+}
+
+// finish splits after the last message; the first CipherState always
+// encrypts from initiator to responder
+func (hs *HandshakeState) finish() (*CipherState, *CipherState) {
+	if hs.msgIdx < len(hs.messages) {
+		return nil, nil
+	}
+	c1, c2 := hs.ss.Split()
+	if hs.initiator {
+		return c1, c2
+	}
+	return c2, c1
+}
+
+/*************************************************
+* Name:        (*HandshakeState).WriteMessage
+*
+* Description: Writes the next handshake message, appending it to out
+*
+* Arguments:   - out []byte: buffer to append to
+*              - payload []byte: message payload
+*
+* Returns      - msg []byte: out with the message appended
+*              - send, recv *CipherState: the transport states after
+*                the last message of the pattern, nil before
+*              - err error: non-nil on failure
+**************************************************/
+func (hs *HandshakeState) WriteMessage(out, payload []byte) ([]byte, *CipherState, *CipherState, error) {
+	if hs.msgIdx >= len(hs.messages) {
+		return nil, nil, nil, ErrHandshakeDone
+	}
+	if !hs.myTurn() {
+		return nil, nil, nil, ErrUnexpectedTurn
+	}
+	start := len(out)
+
+	for _, token := range hs.messages[hs.msgIdx] {
+		switch token {
+		case tokenE:
+			e, err := GenerateKeyPair(hs.params, hs.rand)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			hs.e = e
+			out = append(out, e.Public...)
+			hs.ss.MixHash(e.Public)
+		case tokenS:
+			ct, err := hs.ss.EncryptAndHash(hs.s.Public)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			out = append(out, ct...)
+		case tokenEkem:
+			ct, k, err := kyber.Crypto_kem_enc_rand(hs.params, hs.re, hs.rand)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			out = append(out, ct...)
+			hs.ss.MixHash(ct)
+			hs.ss.MixKey(k)
+		case tokenSkem:
+			ct, k, err := kyber.Crypto_kem_enc_rand(hs.params, hs.rs, hs.rand)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			ect, err := hs.ss.EncryptAndHash(ct)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			out = append(out, ect...)
+			hs.ss.MixKey(k)
+		}
+	}
+
+	ct, err := hs.ss.EncryptAndHash(payload)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	out = append(out, ct...)
+	if len(out)-start > MaxMsgLen {
+		return nil, nil, nil, ErrMessageTooLong
+	}
+	hs.msgIdx++
+	send, recv := hs.finish()
+	return out, send, recv, nil
+}
+
+/*************************************************
+* Name:        (*HandshakeState).ReadMessage
+*
+* Description: Reads the next handshake message, appending its
+*              payload to out
+*
+* Arguments:   - out []byte: buffer to append to
+*              - msg []byte: received message
+*
+* Returns      - payload []byte: out with the payload appended
+*              - send, recv *CipherState: the transport states after
+*                the last message of the pattern, nil before
+*              - err error: non-nil on invalid messages; the state is
+*                unusable afterwards
+**************************************************/
+func (hs *HandshakeState) ReadMessage(out, msg []byte) ([]byte, *CipherState, *CipherState, error) {
+	if hs.msgIdx >= len(hs.messages) {
+		return nil, nil, nil, ErrHandshakeDone
+	}
+	if hs.myTurn() {
+		return nil, nil, nil, ErrUnexpectedTurn
+	}
+	if len(msg) > MaxMsgLen {
+		return nil, nil, nil, ErrMessageTooLong
+	}
+
+	next := func(n int) ([]byte, error) {
+		if len(msg) < n {
+			return nil, ErrShortMessage
+		}
+		b := msg[:n]
+		msg = msg[n:]
+		return b, nil
+	}
+	withTag := func(n int) int {
+		if hs.ss.cs.HasKey() {
+			return n + tagLen
+		}
+		return n
+	}
+
+	for _, token := range hs.messages[hs.msgIdx] {
+		switch token {
+		case tokenE:
+			re, err := next(hs.params.KYBER_PUBLICKEYBYTES)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if err := kyber.Crypto_kem_check_pk(hs.params, re); err != nil {
+				return nil, nil, nil, err
+			}
+			hs.re = append([]byte{}, re...)
+			hs.ss.MixHash(hs.re)
+		case tokenS:
+			ct, err := next(withTag(hs.params.KYBER_PUBLICKEYBYTES))
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			rs, err := hs.ss.DecryptAndHash(ct)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if err := kyber.Crypto_kem_check_pk(hs.params, rs); err != nil {
+				return nil, nil, nil, err
+			}
+			hs.rs = rs
+		case tokenEkem:
+			ct, err := next(hs.params.KYBER_CIPHERTEXTBYTES)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			k, err := kyber.Crypto_kem_dec_checked(hs.params, ct, hs.e.Private)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			hs.ss.MixHash(ct)
+			hs.ss.MixKey(k)
+		case tokenSkem:
+			ect, err := next(withTag(hs.params.KYBER_CIPHERTEXTBYTES))
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			ct, err := hs.ss.DecryptAndHash(ect)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			k, err := kyber.Crypto_kem_dec_checked(hs.params, ct, hs.s.Private)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			hs.ss.MixKey(k)
+		}
+	}
+
+	payload, err := hs.ss.DecryptAndHash(msg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	out = append(out, payload...)
+	hs.msgIdx++
+	send, recv := hs.finish()
+	return out, send, recv, nil
+}
+
+// PeerStatic returns the remote static public key, once known
+func (hs *HandshakeState) PeerStatic() []byte {
+	return append([]byte{}, hs.rs...)
+}
+
+// ChannelBinding returns the handshake hash h
+func (hs *HandshakeState) ChannelBinding() []byte {
+	return hs.ss.GetHandshakeHash()
+}
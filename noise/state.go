@@ -0,0 +1,199 @@
+// Package noise implements the Noise protocol framework state machines
+// (CipherState, SymmetricState, HandshakeState) with the post-quantum
+// handshake patterns of PQNoise, where Kyber/ML-KEM replaces
+// Diffie-Hellman. The cipher is ChaChaPoly and the hash SHA256.
+package noise
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	hashLen = sha256.Size
+	keyLen  = chacha20poly1305.KeySize
+	tagLen  = chacha20poly1305.Overhead
+)
+
+var (
+	ErrNonceExhausted = errors.New("noise: nonce exhausted")
+	ErrDecrypt        = errors.New("noise: message authentication failed")
+	ErrShortMessage   = errors.New("noise: message too short")
+	ErrHandshakeDone  = errors.New("noise: handshake already complete")
+	ErrUnexpectedTurn = errors.New("noise: not this party's turn")
+	ErrMissingKey     = errors.New("noise: required key missing")
+	ErrMessageTooLong = errors.New("noise: message exceeds MaxMsgLen")
+)
+
+// MaxMsgLen is the maximum Noise message length
+const MaxMsgLen = 65535
+
+// CipherState is a ChaChaPoly key with its nonce counter
+type CipherState struct {
+	aead cipher.AEAD // nil until a key is set
+	n    uint64
+}
+
+// InitializeKey sets the key and resets the nonce
+func (cs *CipherState) InitializeKey(k []byte) {
+	cs.aead, _ = chacha20poly1305.New(k[:keyLen])
+	cs.n = 0
+}
+
+// HasKey reports whether a key has been set
+func (cs *CipherState) HasKey() bool {
+	return cs.aead != nil
+}
+
+// SetNonce sets the nonce counter
+func (cs *CipherState) SetNonce(n uint64) {
+	cs.n = n
+}
+
+func (cs *CipherState) nonce() []byte {
+	// 32 bits of zeros followed by the little-endian counter
+	var nonce [chacha20poly1305.NonceSize]byte
+	binary.LittleEndian.PutUint64(nonce[4:], cs.n)
+	return nonce[:]
+}
+
+// EncryptWithAd encrypts plaintext, or returns it unchanged if no key is set
+func (cs *CipherState) EncryptWithAd(ad, plaintext []byte) ([]byte, error) {
+	if !cs.HasKey() {
+		return append([]byte{}, plaintext...), nil
+	}
+	// 2^64-1 is reserved
+	if cs.n == ^uint64(0) {
+		return nil, ErrNonceExhausted
+	}
+	ct := cs.aead.Seal(nil, cs.nonce(), plaintext, ad)
+	cs.n++
+	return ct, nil
+}
+
+// DecryptWithAd decrypts ciphertext, or returns it unchanged if no key is
+// set. The nonce only advances on success
+func (cs *CipherState) DecryptWithAd(ad, ciphertext []byte) ([]byte, error) {
+	if !cs.HasKey() {
+		return append([]byte{}, ciphertext...), nil
+	}
+	if cs.n == ^uint64(0) {
+		return nil, ErrNonceExhausted
+	}
+	pt, err := cs.aead.Open(nil, cs.nonce(), ciphertext, ad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	cs.n++
+	return pt, nil
+}
+
+// Rekey replaces the key with ENCRYPT(k, maxnonce, zerolen, zeros)
+func (cs *CipherState) Rekey() {
+	var nonce [chacha20poly1305.NonceSize]byte
+	binary.LittleEndian.PutUint64(nonce[4:], ^uint64(0))
+	k := cs.aead.Seal(nil, nonce[:], make([]byte, keyLen), nil)
+	cs.aead, _ = chacha20poly1305.New(k[:keyLen])
+}
+
+// Encrypt is EncryptWithAd for transport messages
+func (cs *CipherState) Encrypt(ad, plaintext []byte) ([]byte, error) {
+	return cs.EncryptWithAd(ad, plaintext)
+}
+
+// Decrypt is DecryptWithAd for transport messages
+func (cs *CipherState) Decrypt(ad, ciphertext []byte) ([]byte, error) {
+	return cs.DecryptWithAd(ad, ciphertext)
+}
+
+// SymmetricState holds the chaining key and handshake hash
+type SymmetricState struct {
+	cs CipherState
+	ck []byte
+	h  []byte
+}
+
+// InitializeSymmetric starts from the protocol name
+func (ss *SymmetricState) InitializeSymmetric(protocolName []byte) {
+	if len(protocolName) <= hashLen {
+		ss.h = make([]byte, hashLen)
+		copy(ss.h, protocolName)
+	} else {
+		sum := sha256.Sum256(protocolName)
+		ss.h = sum[:]
+	}
+	ss.ck = append([]byte{}, ss.h...)
+	ss.cs = CipherState{}
+}
+
+// MixKey mixes input key material into the chaining key and sets a new key
+func (ss *SymmetricState) MixKey(ikm []byte) {
+	ck, k := hkdf2(ss.ck, ikm)
+	ss.ck = ck
+	ss.cs.InitializeKey(k)
+}
+
+// MixHash sets h = HASH(h || data)
+func (ss *SymmetricState) MixHash(data []byte) {
+	h := sha256.New()
+	h.Write(ss.h)
+	h.Write(data)
+	ss.h = h.Sum(nil)
+}
+
+// EncryptAndHash encrypts plaintext with h as associated data and mixes
+// the ciphertext into h
+func (ss *SymmetricState) EncryptAndHash(plaintext []byte) ([]byte, error) {
+	ct, err := ss.cs.EncryptWithAd(ss.h, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	ss.MixHash(ct)
+	return ct, nil
+}
+
+// DecryptAndHash is the inverse of EncryptAndHash
+func (ss *SymmetricState) DecryptAndHash(ciphertext []byte) ([]byte, error) {
+	pt, err := ss.cs.DecryptWithAd(ss.h, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	ss.MixHash(ciphertext)
+	return pt, nil
+}
+
+// Split derives the two transport CipherStates
+func (ss *SymmetricState) Split() (*CipherState, *CipherState) {
+	k1, k2 := hkdf2(ss.ck, nil)
+	c1, c2 := &CipherState{}, &CipherState{}
+	c1.InitializeKey(k1)
+	c2.InitializeKey(k2)
+	return c1, c2
+}
+
+// GetHandshakeHash returns h, for channel binding
+func (ss *SymmetricState) GetHandshakeHash() []byte {
+	return append([]byte{}, ss.h...)
+}
+
+// hkdf2 is the two-output HKDF of the Noise specification
+func hkdf2(ck, ikm []byte) ([]byte, []byte) {
+	mac := hmac.New(sha256.New, ck)
+	mac.Write(ikm)
+	tempKey := mac.Sum(nil)
+
+	mac = hmac.New(sha256.New, tempKey)
+	mac.Write([]byte{0x01})
+	out1 := mac.Sum(nil)
+
+	mac = hmac.New(sha256.New, tempKey)
+	mac.Write(out1)
+	mac.Write([]byte{0x02})
+	out2 := mac.Sum(nil)
+	return out1, out2
+}
@@ -7,6 +7,8 @@ const (
 	KYBER_SYMBYTES = 32 /* size in bytes of hashes, and seeds */
 	KYBER_SSBYTES  = 32 /* size in bytes of shared key */
 
+	KYBER_SEEDBYTES = 2 * KYBER_SYMBYTES /* size in bytes of key generation seed d||z */
+
 	KYBER_POLYBYTES = 384
 
 	KYBER_ETA2 = 2
@@ -14,9 +16,16 @@ const (
 	KYBER_INDCPA_MSGBYTES = (KYBER_SYMBYTES)
 )
 
+const (
+	KYBER_MODE_ROUND3 = 0 /* round-3 Kyber submission (Kyber512/768/1024) */
+	KYBER_MODE_MLKEM  = 1 /* NIST FIPS 203 (ML-KEM-512/768/1024) */
+)
+
 type Parameters struct {
 	KYBER_K    int
 	KYBER_NAME string
+	KYBER_MODE int
+	KYBER_ID   byte /* stable wire identifier, KYBER_MODE<<4 | KYBER_K */
 
 	KYBER_ETA1                   int
 	KYBER_POLYCOMPRESSEDBYTES    int
@@ -33,6 +42,14 @@ type Parameters struct {
 }
 
 func NewParameters(k int) *Parameters { /* Change k for different security strengths */
+	return newParameters(k, KYBER_MODE_ROUND3)
+}
+
+func NewMLKEMParameters(k int) *Parameters { /* FIPS 203 ML-KEM, k as in NewParameters */
+	return newParameters(k, KYBER_MODE_MLKEM)
+}
+
+func newParameters(k int, mode int) *Parameters {
 	var params Parameters
 	switch k {
 	case 2:
@@ -61,6 +78,12 @@ func NewParameters(k int) *Parameters { /* Change k for different security stren
 		params.KYBER_POLYVECCOMPRESSEDBYTES = (params.KYBER_K * 320)
 	}
 
+	params.KYBER_MODE = mode
+	params.KYBER_ID = byte(mode<<4 | params.KYBER_K)
+	if mode == KYBER_MODE_MLKEM {
+		params.KYBER_NAME = "ML-KEM-" + params.KYBER_NAME[len("Kyber"):]
+	}
+
 	params.KYBER_POLYVECBYTES = (params.KYBER_K * KYBER_POLYBYTES)
 
 	params.KYBER_INDCPA_PUBLICKEYBYTES = (params.KYBER_POLYVECBYTES + KYBER_SYMBYTES)
@@ -72,5 +95,44 @@ func NewParameters(k int) *Parameters { /* Change k for different security stren
 	params.KYBER_CIPHERTEXTBYTES = (params.KYBER_INDCPA_BYTES)
 
 	return &params
+}
 
+/*************************************************
+* Name:        ParametersByName
+*
+* Description: Looks up a parameter set by its KYBER_NAME,
+*              e.g. "Kyber768" or "ML-KEM-768"
+*
+* Arguments:   - name string: input parameter set name
+*
+* Returns      - params *Parameters: output parameters struct
+*              - err error: ErrUnsupportedParameters for unknown names
+**************************************************/
+func ParametersByName(name string) (*Parameters, error) {
+	for _, mode := range []int{KYBER_MODE_ROUND3, KYBER_MODE_MLKEM} {
+		for k := 2; k <= 4; k++ {
+			if params := newParameters(k, mode); params.KYBER_NAME == name {
+				return params, nil
+			}
+		}
+	}
+	return nil, ErrUnsupportedParameters
+}
+
+/*************************************************
+* Name:        ParametersByID
+*
+* Description: Looks up a parameter set by its KYBER_ID
+*
+* Arguments:   - id byte: input parameter set identifier
+*
+* Returns      - params *Parameters: output parameters struct
+*              - err error: ErrUnsupportedParameters for unknown ids
+**************************************************/
+func ParametersByID(id byte) (*Parameters, error) {
+	mode, k := int(id>>4), int(id&0x0f)
+	if (mode != KYBER_MODE_ROUND3 && mode != KYBER_MODE_MLKEM) || k < 2 || k > 4 {
+		return nil, ErrUnsupportedParameters
+	}
+	return newParameters(k, mode), nil
 }
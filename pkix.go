@@ -0,0 +1,276 @@
+package kyber
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// NIST OIDs of the FIPS 203 parameter sets (id-alg-ml-kem-*)
+var (
+	oidMLKEM512  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 4, 1}
+	oidMLKEM768  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 4, 2}
+	oidMLKEM1024 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 4, 3}
+)
+
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+type pkcs8 struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+	// optional attributes omitted
+}
+
+// both CHOICE of ML-KEM-PrivateKey
+type mlkemPrivateKeyBoth struct {
+	Seed        []byte
+	ExpandedKey []byte
+}
+
+func oidFromParameters(params *Parameters) (asn1.ObjectIdentifier, error) {
+	if params.KYBER_MODE != KYBER_MODE_MLKEM {
+		return nil, ErrUnsupportedParameters
+	}
+	switch params.KYBER_K {
+	case 2:
+		return oidMLKEM512, nil
+	case 3:
+		return oidMLKEM768, nil
+	case 4:
+		return oidMLKEM1024, nil
+	}
+	return nil, ErrUnsupportedParameters
+}
+
+func parametersFromAlgorithm(algo pkix.AlgorithmIdentifier) (*Parameters, error) {
+	// Parameters MUST be absent for ML-KEM
+	if len(algo.Parameters.FullBytes) != 0 {
+		return nil, errors.New("kyber: unexpected ML-KEM algorithm parameters")
+	}
+	switch {
+	case algo.Algorithm.Equal(oidMLKEM512):
+		return NewMLKEMParameters(2), nil
+	case algo.Algorithm.Equal(oidMLKEM768):
+		return NewMLKEMParameters(3), nil
+	case algo.Algorithm.Equal(oidMLKEM1024):
+		return NewMLKEMParameters(4), nil
+	}
+	return nil, fmt.Errorf("kyber: unknown algorithm OID %s", algo.Algorithm)
+}
+
+/*************************************************
+* Name:        MarshalPKIXPublicKey
+*
+* Description: Encodes an ML-KEM public key as DER
+*              SubjectPublicKeyInfo
+*
+* Arguments:   - pub *PublicKey: input public key
+*
+* Returns      - der []byte: output SubjectPublicKeyInfo
+*              - err error: ErrUnsupportedParameters for
+*                round-3 Kyber keys, which have no OID
+**************************************************/
+func MarshalPKIXPublicKey(pub *PublicKey) ([]byte, error) {
+	oid, err := oidFromParameters(pub.params)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oid},
+		PublicKey: asn1.BitString{Bytes: pub.Bytes(), BitLength: 8 * len(pub.pk)},
+	})
+}
+
+/*************************************************
+* Name:        ParsePKIXPublicKey
+*
+* Description: Parses a DER SubjectPublicKeyInfo holding an
+*              ML-KEM public key, applying crypto_kem_check_pk
+*
+* Arguments:   - der []byte: input SubjectPublicKeyInfo
+*
+* Returns      - pub *PublicKey: output public key
+*              - err error: non-nil on invalid input
+**************************************************/
+func ParsePKIXPublicKey(der []byte) (*PublicKey, error) {
+	var spki subjectPublicKeyInfo
+	if rest, err := asn1.Unmarshal(der, &spki); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, errors.New("kyber: trailing data after SubjectPublicKeyInfo")
+	}
+	params, err := parametersFromAlgorithm(spki.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if spki.PublicKey.BitLength%8 != 0 {
+		return nil, errors.New("kyber: public key BIT STRING has unused bits")
+	}
+	return ParsePublicKey(params, spki.PublicKey.Bytes)
+}
+
+/*************************************************
+* Name:        MarshalPKCS8PrivateKey
+*
+* Description: Encodes an ML-KEM private key as DER PKCS #8
+*              OneAsymmetricKey. The seed choice is used when
+*              the seed is known, the expandedKey choice otherwise
+*
+* Arguments:   - priv *PrivateKey: input private key
+*
+* Returns      - der []byte: output PKCS #8 private key
+*              - err error: ErrUnsupportedParameters for
+*                round-3 Kyber keys, which have no OID
+**************************************************/
+func MarshalPKCS8PrivateKey(priv *PrivateKey) ([]byte, error) {
+	oid, err := oidFromParameters(priv.params)
+	if err != nil {
+		return nil, err
+	}
+
+	var key []byte
+	if priv.seed != nil {
+		key, err = asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: priv.seed})
+	} else {
+		key, err = asn1.Marshal(priv.sk)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(pkcs8{
+		Algo:       pkix.AlgorithmIdentifier{Algorithm: oid},
+		PrivateKey: key,
+	})
+}
+
+/*************************************************
+* Name:        ParsePKCS8PrivateKey
+*
+* Description: Parses a DER PKCS #8 ML-KEM private key in any of
+*              the seed, expandedKey or both choices. For both,
+*              the seed must expand to the stored expanded key
+*
+* Arguments:   - der []byte: input PKCS #8 private key
+*
+* Returns      - priv *PrivateKey: output private key
+*              - err error: non-nil on invalid input
+**************************************************/
+func ParsePKCS8PrivateKey(der []byte) (*PrivateKey, error) {
+	var p pkcs8
+	if rest, err := asn1.Unmarshal(der, &p); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, errors.New("kyber: trailing data after PKCS #8 private key")
+	}
+	if p.Version != 0 && p.Version != 1 {
+		return nil, fmt.Errorf("kyber: unsupported PKCS #8 version %d", p.Version)
+	}
+	params, err := parametersFromAlgorithm(p.Algo)
+	if err != nil {
+		return nil, err
+	}
+
+	var choice asn1.RawValue
+	if rest, err := asn1.Unmarshal(p.PrivateKey, &choice); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, errors.New("kyber: trailing data after ML-KEM private key")
+	}
+
+	switch {
+	case choice.Class == asn1.ClassContextSpecific && choice.Tag == 0 && !choice.IsCompound:
+		return NewPrivateKeyFromSeed(params, choice.Bytes)
+	case choice.Class == asn1.ClassUniversal && choice.Tag == asn1.TagOctetString && !choice.IsCompound:
+		return ParsePrivateKey(params, choice.Bytes)
+	case choice.Class == asn1.ClassUniversal && choice.Tag == asn1.TagSequence && choice.IsCompound:
+		var both mlkemPrivateKeyBoth
+		if rest, err := asn1.Unmarshal(choice.FullBytes, &both); err != nil {
+			return nil, err
+		} else if len(rest) != 0 {
+			return nil, errors.New("kyber: trailing data after ML-KEM private key")
+		}
+		return ParsePrivateKeyWithSeed(params, both.ExpandedKey, both.Seed)
+	}
+	return nil, errors.New("kyber: unknown ML-KEM private key encoding")
+}
+
+/*************************************************
+* Name:        EncodePublicKeyPEM
+*
+* Description: Encodes an ML-KEM public key as a PEM
+*              "PUBLIC KEY" block
+*
+* Arguments:   - pub *PublicKey: input public key
+*
+* Returns      - out []byte: output PEM data
+*              - err error: non-nil on failure
+**************************************************/
+func EncodePublicKeyPEM(pub *PublicKey) ([]byte, error) {
+	der, err := MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+/*************************************************
+* Name:        DecodePublicKeyPEM
+*
+* Description: Parses the first PEM "PUBLIC KEY" block of data
+*
+* Arguments:   - data []byte: input PEM data
+*
+* Returns      - pub *PublicKey: output public key
+*              - err error: non-nil on invalid input
+**************************************************/
+func DecodePublicKeyPEM(data []byte) (*PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.New("kyber: no PEM PUBLIC KEY block found")
+	}
+	return ParsePKIXPublicKey(block.Bytes)
+}
+
+/*************************************************
+* Name:        EncodePrivateKeyPEM
+*
+* Description: Encodes an ML-KEM private key as a PEM
+*              "PRIVATE KEY" block
+*
+* Arguments:   - priv *PrivateKey: input private key
+*
+* Returns      - out []byte: output PEM data
+*              - err error: non-nil on failure
+**************************************************/
+func EncodePrivateKeyPEM(priv *PrivateKey) ([]byte, error) {
+	der, err := MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+/*************************************************
+* Name:        DecodePrivateKeyPEM
+*
+* Description: Parses the first PEM "PRIVATE KEY" block of data
+*
+* Arguments:   - data []byte: input PEM data
+*
+* Returns      - priv *PrivateKey: output private key
+*              - err error: non-nil on invalid input
+**************************************************/
+func DecodePrivateKeyPEM(data []byte) (*PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errors.New("kyber: no PEM PRIVATE KEY block found")
+	}
+	return ParsePKCS8PrivateKey(block.Bytes)
+}
@@ -0,0 +1,117 @@
+package kyber
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func Test_PKIX(t *testing.T) {
+	for k := 2; k <= 4; k++ {
+		params := NewMLKEMParameters(k)
+		priv, err := GenerateKey(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub := priv.PublicKey()
+
+		der, err := MarshalPKIXPublicKey(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub2, err := ParsePKIXPublicKey(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pub.Equal(pub2) {
+			t.Errorf("%s: public key round trip failed", params.KYBER_NAME)
+		}
+
+		pemdata, err := EncodePrivateKeyPEM(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		priv2, err := DecodePrivateKeyPEM(pemdata)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !priv.Equal(priv2) {
+			t.Errorf("%s: private key PEM round trip failed", params.KYBER_NAME)
+		}
+
+		pemdata, err = EncodePublicKeyPEM(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pub2, err = DecodePublicKeyPEM(pemdata); err != nil || !pub.Equal(pub2) {
+			t.Errorf("%s: public key PEM round trip failed: %v", params.KYBER_NAME, err)
+		}
+	}
+}
+
+func Test_PKIX_encodings(t *testing.T) {
+	params := NewMLKEMParameters(2)
+	priv, _ := GenerateKey(params)
+	seed, _ := priv.Seed()
+
+	// SEQUENCE { INTEGER 0, SEQUENCE { OID id-alg-ml-kem-512 }, OCTET STRING { [0] seed } }
+	der, err := MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix, _ := hex.DecodeString("3054020100300b060960864801650304040104428040")
+	if !bytes.HasPrefix(der, prefix) || !bytes.Equal(der[len(prefix):], seed) {
+		t.Errorf("unexpected seed encoding %x", der)
+	}
+
+	// SEQUENCE { SEQUENCE { OID id-alg-ml-kem-512 }, BIT STRING pk }
+	der, _ = MarshalPKIXPublicKey(priv.PublicKey())
+	prefix, _ = hex.DecodeString("30820332300b06096086480165030404010382032100")
+	if !bytes.HasPrefix(der, prefix) {
+		t.Errorf("unexpected public key encoding %x", der[:32])
+	}
+
+	// The expandedKey choice carries no seed
+	expanded, _ := ParsePrivateKey(params, priv.Bytes())
+	der, err = MarshalPKCS8PrivateKey(expanded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv2, err := ParsePKCS8PrivateKey(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !priv.Equal(priv2) {
+		t.Error("expandedKey round trip failed")
+	}
+	if _, err := priv2.Seed(); !errors.Is(err, ErrSeedUnavailable) {
+		t.Errorf("expandedKey seed: got %v", err)
+	}
+
+	// The both choice must be consistent
+	both := func(seed, sk []byte) []byte {
+		key, _ := asn1.Marshal(mlkemPrivateKeyBoth{Seed: seed, ExpandedKey: sk})
+		der, _ := asn1.Marshal(pkcs8{Algo: pkix.AlgorithmIdentifier{Algorithm: oidMLKEM512}, PrivateKey: key})
+		return der
+	}
+	priv3, err := ParsePKCS8PrivateKey(both(seed, priv.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := priv3.Seed(); !priv.Equal(priv3) || !bytes.Equal(s, seed) {
+		t.Error("both round trip failed")
+	}
+	other, _ := GenerateKey(params)
+	if _, err := ParsePKCS8PrivateKey(both(seed, other.Bytes())); !errors.Is(err, ErrSeedMismatch) {
+		t.Errorf("inconsistent both: got %v", err)
+	}
+
+	// Round-3 Kyber has no OID
+	kyber, _ := GenerateKey(NewParameters(3))
+	if _, err := MarshalPKIXPublicKey(kyber.PublicKey()); !errors.Is(err, ErrUnsupportedParameters) {
+		t.Errorf("Kyber768 public key: got %v", err)
+	}
+}
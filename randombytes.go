@@ -1,9 +1,23 @@
 package kyber
 
-import "crypto/rand"
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+)
 
 func randombytes(outlen int) []byte {
-	out := make([]byte, outlen)
-	rand.Read(out[:outlen]) //randombytes(buf, KYBER_SYMBYTES) to generate the seed
+	out, err := randombytes_from(rand.Reader, outlen)
+	if err != nil {
+		panic(err) // never hand out keys derived from a zeroed buffer
+	}
 	return out
 }
+
+func randombytes_from(r io.Reader, outlen int) ([]byte, error) {
+	out := make([]byte, outlen)
+	if _, err := io.ReadFull(r, out); err != nil {
+		return nil, fmt.Errorf("kyber: reading randomness: %w", err)
+	}
+	return out, nil
+}
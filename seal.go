@@ -0,0 +1,141 @@
+package kyber
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+/* Sealed message layout:
+ *   version (1) || KYBER_ID (1) || KEM cipher text || AES-256-GCM cipher text
+ * The AEAD key and nonce are HKDF-SHA256(ss, salt = KEM cipher text,
+ * info = sealLabel || version || KYBER_ID). */
+const (
+	SEAL_VERSION     = 1
+	SEAL_HEADERBYTES = 2
+	SEAL_OVERHEAD    = SEAL_HEADERBYTES + 16 /* plus KYBER_CIPHERTEXTBYTES */
+
+	sealLabel = "kyber-go seal"
+)
+
+func sealAEAD(ss []byte, ct []byte, header []byte) (cipher.AEAD, []byte, error) {
+	info := append([]byte(sealLabel), header...)
+	okm := make([]byte, 32+12)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ss, ct, info), okm); err != nil {
+		return nil, nil, err
+	}
+	block, err := aes.NewCipher(okm[:32])
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	return aead, okm[32:], nil
+}
+
+/*************************************************
+* Name:        Seal
+*
+* Description: Encrypts a message of any length to pub: encapsulates,
+*              derives an AES-256-GCM key and nonce from the shared
+*              secret and cipher text, and encrypts plaintext
+*
+* Arguments:   - pub *PublicKey: recipient public key
+*              - plaintext []byte: input message
+*              - aad []byte: additional authenticated data, not
+*                included in the output
+*
+* Returns      - sealed []byte: output sealed message of
+*                SEAL_OVERHEAD + KYBER_CIPHERTEXTBYTES + len(plaintext) bytes
+*              - err error: non-nil on failure
+**************************************************/
+func Seal(pub *PublicKey, plaintext []byte, aad []byte) ([]byte, error) {
+	return SealRand(pub, plaintext, aad, rand.Reader)
+}
+
+// SealRand is Seal drawing its randomness from rand
+func SealRand(pub *PublicKey, plaintext []byte, aad []byte, rand io.Reader) ([]byte, error) {
+	ct, ss, err := pub.EncapsulateRand(rand)
+	if err != nil {
+		return nil, err
+	}
+	header := []byte{SEAL_VERSION, pub.params.KYBER_ID}
+	aead, nonce, err := sealAEAD(ss, ct, header)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, SEAL_OVERHEAD+len(ct)+len(plaintext))
+	out = append(out, header...)
+	out = append(out, ct...)
+	return aead.Seal(out, nonce, plaintext, aad), nil
+}
+
+/*************************************************
+* Name:        SealedParameters
+*
+* Description: Returns the parameter set recorded in a sealed message
+*
+* Arguments:   - sealed []byte: input sealed message
+*
+* Returns      - params *Parameters: output parameters struct
+*              - err error: ErrInvalidSealedMessage on bad input
+**************************************************/
+func SealedParameters(sealed []byte) (*Parameters, error) {
+	if len(sealed) < SEAL_HEADERBYTES || sealed[0] != SEAL_VERSION {
+		return nil, ErrInvalidSealedMessage
+	}
+	params, err := ParametersByID(sealed[1])
+	if err != nil {
+		return nil, ErrInvalidSealedMessage
+	}
+	return params, nil
+}
+
+/*************************************************
+* Name:        Open
+*
+* Description: Decrypts a message produced by Seal
+*
+* Arguments:   - priv *PrivateKey: recipient private key
+*              - sealed []byte: input sealed message
+*              - aad []byte: additional authenticated data given to Seal
+*
+* Returns      - plaintext []byte: output message
+*              - err error: ErrParametersMismatch if sealed was made for
+*                another parameter set, ErrDecryption if it was not
+*                made for priv or was modified
+**************************************************/
+func Open(priv *PrivateKey, sealed []byte, aad []byte) ([]byte, error) {
+	params, err := SealedParameters(sealed)
+	if err != nil {
+		return nil, err
+	}
+	if !sameParameters(params, priv.params) {
+		return nil, ErrParametersMismatch
+	}
+	if len(sealed) < SEAL_OVERHEAD+params.KYBER_CIPHERTEXTBYTES {
+		return nil, ErrInvalidSealedMessage
+	}
+	header := sealed[:SEAL_HEADERBYTES]
+	ct := sealed[SEAL_HEADERBYTES : SEAL_HEADERBYTES+params.KYBER_CIPHERTEXTBYTES]
+
+	ss, err := priv.Decapsulate(ct)
+	if err != nil {
+		return nil, err
+	}
+	aead, nonce, err := sealAEAD(ss, ct, header)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, sealed[SEAL_HEADERBYTES+params.KYBER_CIPHERTEXTBYTES:], aad)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	return plaintext, nil
+}
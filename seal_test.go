@@ -0,0 +1,84 @@
+package kyber
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func Test_Seal(t *testing.T) {
+	for _, params := range []*Parameters{NewParameters(2), NewMLKEMParameters(3), NewMLKEMParameters(4)} {
+		priv, err := GenerateKey(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, msg := range [][]byte{nil, []byte("hello"), bytes.Repeat([]byte{0xaa}, 100000)} {
+			sealed, err := Seal(priv.PublicKey(), msg, []byte("aad"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(sealed) != SEAL_OVERHEAD+params.KYBER_CIPHERTEXTBYTES+len(msg) {
+				t.Errorf("%s: sealed message is %d bytes", params.KYBER_NAME, len(sealed))
+			}
+			sp, err := SealedParameters(sealed)
+			if err != nil || sp.KYBER_NAME != params.KYBER_NAME {
+				t.Errorf("%s: SealedParameters = %v, %v", params.KYBER_NAME, sp, err)
+			}
+			pt, err := Open(priv, sealed, []byte("aad"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(pt, msg) {
+				t.Errorf("%s: opened message differs", params.KYBER_NAME)
+			}
+		}
+
+		sealed, _ := Seal(priv.PublicKey(), []byte("hello"), nil)
+		if _, err := Open(priv, sealed, []byte("other")); !errors.Is(err, ErrDecryption) {
+			t.Errorf("%s: wrong aad: got %v", params.KYBER_NAME, err)
+		}
+		for _, i := range []int{SEAL_HEADERBYTES, len(sealed) - 1} {
+			bad := append([]byte{}, sealed...)
+			bad[i] ^= 1
+			if _, err := Open(priv, bad, nil); !errors.Is(err, ErrDecryption) {
+				t.Errorf("%s: tampered byte %d: got %v", params.KYBER_NAME, i, err)
+			}
+		}
+		if _, err := Open(priv, sealed[:SEAL_OVERHEAD], nil); !errors.Is(err, ErrInvalidSealedMessage) {
+			t.Errorf("%s: truncated: got %v", params.KYBER_NAME, err)
+		}
+		other, _ := GenerateKey(params)
+		if _, err := Open(other, sealed, nil); !errors.Is(err, ErrDecryption) {
+			t.Errorf("%s: wrong key: got %v", params.KYBER_NAME, err)
+		}
+	}
+
+	// the header names the parameter set, so a key of another set is refused
+	priv, _ := GenerateKey(NewParameters(3))
+	sealed, _ := Seal(priv.PublicKey(), []byte("hello"), nil)
+	mlkem, _ := GenerateKey(NewMLKEMParameters(3))
+	if _, err := Open(mlkem, sealed, nil); !errors.Is(err, ErrParametersMismatch) {
+		t.Errorf("mismatched parameters: got %v", err)
+	}
+	sealed[0] = SEAL_VERSION + 1
+	if _, err := Open(priv, sealed, nil); !errors.Is(err, ErrInvalidSealedMessage) {
+		t.Errorf("unknown version: got %v", err)
+	}
+}
+
+func Test_ParametersByID(t *testing.T) {
+	for _, mode := range []int{KYBER_MODE_ROUND3, KYBER_MODE_MLKEM} {
+		for k := 2; k <= 4; k++ {
+			params := newParameters(k, mode)
+			got, err := ParametersByID(params.KYBER_ID)
+			if err != nil || got.KYBER_NAME != params.KYBER_NAME {
+				t.Errorf("ParametersByID(%#02x) = %v, %v", params.KYBER_ID, got, err)
+			}
+		}
+	}
+	for _, id := range []byte{0x00, 0x01, 0x05, 0x21, 0xff} {
+		if _, err := ParametersByID(id); err != ErrUnsupportedParameters {
+			t.Errorf("ParametersByID(%#02x) accepted", id)
+		}
+	}
+}
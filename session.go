@@ -0,0 +1,383 @@
+package kyber
+
+import (
+	"crypto/rand"
+	"io"
+)
+
+/* Session objects for the UAKE and AKE flows of kex.go. They keep the
+ * ephemeral secret key and tk internally, allow each step once and in
+ * order, and zero the ephemeral secrets once the session ends. A session
+ * that failed on a bad message cannot be resumed. FinishConfirm,
+ * RespondConfirm and Confirm add the key confirmation of Kex_confirm. */
+
+const (
+	kexStateInit = iota
+	kexStateSent
+	kexStateDone
+)
+
+// Initiator is party A of a UAKE or AKE session
+type Initiator struct {
+	kexpp *KexParameters
+	ake   bool
+	pkb   []byte // responder static public key
+	ska   []byte // initiator static secret key, AKE only
+	esk   []byte // ephemeral secret key
+	tk    []byte
+	state int
+
+	bound   bool // transcript-bound AKE
+	binding KexBinding
+	send    []byte // first message, for the transcript
+}
+
+// Responder is party B of a UAKE or AKE session
+type Responder struct {
+	kexpp *KexParameters
+	ake   bool
+	skb   []byte // responder static secret key
+	pka   []byte // initiator static public key, AKE only
+	state int
+
+	bound   bool // transcript-bound AKE
+	binding KexBinding
+
+	ck   []byte // confirmed key, until tagA checks
+	tagA []byte // expected tag of A
+}
+
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+/*************************************************
+* Name:        NewUAKEInitiator
+*
+* Description: Creates the initiator of a unilaterally
+*              authenticated key exchange with B
+*
+* Arguments:   - kexpp *KexParameters: Kex parameters struct
+*              - pkb []byte: static public key of B
+*
+* Returns      - a *Initiator: output session
+*              - err error: ErrInvalidPublicKeyLength on bad input
+**************************************************/
+func NewUAKEInitiator(kexpp *KexParameters, pkb []byte) (*Initiator, error) {
+	if len(pkb) != kexpp.CRYPTO_PUBLICKEYBYTES {
+		return nil, ErrInvalidPublicKeyLength
+	}
+	return &Initiator{kexpp: kexpp, pkb: append([]byte{}, pkb...)}, nil
+}
+
+/*************************************************
+* Name:        NewAKEInitiator
+*
+* Description: Creates the initiator of a mutually
+*              authenticated key exchange with B
+*
+* Arguments:   - kexpp *KexParameters: Kex parameters struct
+*              - pkb []byte: static public key of B
+*              - ska []byte: static secret key of A
+*
+* Returns      - a *Initiator: output session
+*              - err error: non-nil on bad key lengths
+**************************************************/
+func NewAKEInitiator(kexpp *KexParameters, pkb []byte, ska []byte) (*Initiator, error) {
+	a, err := NewUAKEInitiator(kexpp, pkb)
+	if err != nil {
+		return nil, err
+	}
+	if len(ska) != kexpp.CRYPTO_SECRETKEYBYTES {
+		return nil, ErrInvalidSecretKeyLength
+	}
+	a.ake = true
+	a.ska = ska
+	return a, nil
+}
+
+/*************************************************
+* Name:        NewBoundAKEInitiator
+*
+* Description: Creates the initiator of a transcript-bound AKE
+*              with B (see Kex_ake_sharedA_bound)
+*
+* Arguments:   - kexpp *KexParameters: Kex parameters struct
+*              - pkb []byte: static public key of B
+*              - ska []byte: static secret key of A
+*              - binding *KexBinding: identities and context, may be nil
+*
+* Returns      - a *Initiator: output session
+*              - err error: non-nil on bad key lengths
+**************************************************/
+func NewBoundAKEInitiator(kexpp *KexParameters, pkb []byte, ska []byte, binding *KexBinding) (*Initiator, error) {
+	a, err := NewAKEInitiator(kexpp, pkb, ska)
+	if err != nil {
+		return nil, err
+	}
+	a.bound = true
+	if binding != nil {
+		a.binding = *binding
+	}
+	return a, nil
+}
+
+/*************************************************
+* Name:        Start
+*
+* Description: Generates the ephemeral key pair, encapsulates
+*              to B and returns the first message
+*
+* Returns      - send []byte: output message of KEX_UAKE_SENDABYTES
+*                or KEX_AKE_SENDABYTES bytes
+*              - err error: ErrKexState if called twice
+**************************************************/
+func (a *Initiator) Start() ([]byte, error) {
+	return a.StartRand(rand.Reader)
+}
+
+// StartRand is Start drawing its randomness from rand
+func (a *Initiator) StartRand(rand io.Reader) ([]byte, error) {
+	if a.state != kexStateInit {
+		return nil, ErrKexState
+	}
+	var send []byte
+	var err error
+	if a.ake {
+		send, a.tk, a.esk, err = Kex_ake_initA_rand(a.kexpp, a.pkb, rand)
+	} else {
+		send, a.tk, a.esk, err = Kex_uake_initA_rand(a.kexpp, a.pkb, rand)
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.send = send
+	a.state = kexStateSent
+	return send, nil
+}
+
+/*************************************************
+* Name:        Finish
+*
+* Description: Processes the answer of B and returns the session
+*              key. The ephemeral secrets are zeroed in all cases,
+*              so Finish can be called only once
+*
+* Arguments:   - recv []byte: message of B
+*
+* Returns      - k []byte: output session key of KEX_SSBYTES bytes
+*              - err error: ErrKexState if Start was not called or the
+*                session ended, ErrInvalidKexMessageLength on bad input
+**************************************************/
+func (a *Initiator) Finish(recv []byte) ([]byte, error) {
+	if a.state != kexStateSent {
+		return nil, ErrKexState
+	}
+	defer a.clear()
+	if a.bound {
+		return Kex_ake_sharedA_bound(a.kexpp, a.send, recv, a.tk, a.esk, a.ska, a.pkb, &a.binding)
+	}
+	if a.ake {
+		if len(recv) != a.kexpp.KEX_AKE_SENDBBYTES {
+			return nil, ErrInvalidKexMessageLength
+		}
+		return Kex_ake_sharedA(a.kexpp, recv, a.tk, a.esk, a.ska), nil
+	}
+	if len(recv) != a.kexpp.KEX_UAKE_SENDBBYTES {
+		return nil, ErrInvalidKexMessageLength
+	}
+	return Kex_uake_sharedA(a.kexpp, recv, a.tk, a.esk), nil
+}
+
+/*************************************************
+* Name:        FinishConfirm
+*
+* Description: Finish for a confirmed session: checks the tag of B
+*              and returns the tag of A (third flight) and the
+*              confirmed session key
+*
+* Arguments:   - recv []byte: message of B
+*              - tagB []byte: confirmation tag of B
+*
+* Returns      - tagA []byte: output tag of KEX_CONFIRMBYTES bytes
+*              - ck []byte: output confirmed key of KEX_SSBYTES bytes
+*              - err error: ErrKexConfirmation if tagB does not check,
+*                as for Finish otherwise
+**************************************************/
+func (a *Initiator) FinishConfirm(recv []byte, tagB []byte) ([]byte, []byte, error) {
+	send := a.send
+	k, err := a.Finish(recv)
+	if err != nil {
+		return nil, nil, err
+	}
+	ck, tagA, want := Kex_confirm(k, send, recv)
+	zeroBytes(k)
+	if err := Kex_confirm_verify(tagB, want); err != nil {
+		zeroBytes(ck)
+		return nil, nil, err
+	}
+	return tagA, ck, nil
+}
+
+// Abort ends the session without a key, zeroing the ephemeral secrets;
+// later calls return ErrKexState
+func (a *Initiator) Abort() {
+	a.clear()
+}
+
+func (a *Initiator) clear() {
+	zeroBytes(a.esk)
+	zeroBytes(a.tk)
+	a.esk, a.tk, a.send = nil, nil, nil
+	a.state = kexStateDone
+}
+
+/*************************************************
+* Name:        NewUAKEResponder
+*
+* Description: Creates the responder of a unilaterally
+*              authenticated key exchange
+*
+* Arguments:   - kexpp *KexParameters: Kex parameters struct
+*              - skb []byte: static secret key of B
+*
+* Returns      - b *Responder: output session
+*              - err error: ErrInvalidSecretKeyLength on bad input
+**************************************************/
+func NewUAKEResponder(kexpp *KexParameters, skb []byte) (*Responder, error) {
+	if len(skb) != kexpp.CRYPTO_SECRETKEYBYTES {
+		return nil, ErrInvalidSecretKeyLength
+	}
+	return &Responder{kexpp: kexpp, skb: skb}, nil
+}
+
+/*************************************************
+* Name:        NewAKEResponder
+*
+* Description: Creates the responder of a mutually
+*              authenticated key exchange with A
+*
+* Arguments:   - kexpp *KexParameters: Kex parameters struct
+*              - skb []byte: static secret key of B
+*              - pka []byte: static public key of A
+*
+* Returns      - b *Responder: output session
+*              - err error: non-nil on bad key lengths
+**************************************************/
+func NewAKEResponder(kexpp *KexParameters, skb []byte, pka []byte) (*Responder, error) {
+	b, err := NewUAKEResponder(kexpp, skb)
+	if err != nil {
+		return nil, err
+	}
+	if len(pka) != kexpp.CRYPTO_PUBLICKEYBYTES {
+		return nil, ErrInvalidPublicKeyLength
+	}
+	b.ake = true
+	b.pka = append([]byte{}, pka...)
+	return b, nil
+}
+
+// NewBoundAKEResponder is NewAKEResponder for the transcript-bound AKE
+// (see Kex_ake_sharedB_bound_rand); binding may be nil
+func NewBoundAKEResponder(kexpp *KexParameters, skb []byte, pka []byte, binding *KexBinding) (*Responder, error) {
+	b, err := NewAKEResponder(kexpp, skb, pka)
+	if err != nil {
+		return nil, err
+	}
+	b.bound = true
+	if binding != nil {
+		b.binding = *binding
+	}
+	return b, nil
+}
+
+/*************************************************
+* Name:        Respond
+*
+* Description: Processes the first message of A and returns the
+*              answer and the session key. A responder answers once
+*
+* Arguments:   - recv []byte: message of A
+*
+* Returns      - send []byte: output message of KEX_UAKE_SENDBBYTES
+*                or KEX_AKE_SENDBBYTES bytes
+*              - k []byte: output session key of KEX_SSBYTES bytes
+*              - err error: ErrKexState if called twice,
+*                ErrInvalidKexMessageLength on bad input
+**************************************************/
+func (b *Responder) Respond(recv []byte) ([]byte, []byte, error) {
+	return b.RespondRand(recv, rand.Reader)
+}
+
+// RespondRand is Respond drawing its randomness from rand
+func (b *Responder) RespondRand(recv []byte, rand io.Reader) ([]byte, []byte, error) {
+	if b.state != kexStateInit {
+		return nil, nil, ErrKexState
+	}
+	b.state = kexStateDone
+	if b.bound {
+		return Kex_ake_sharedB_bound_rand(b.kexpp, recv, b.skb, b.pka, &b.binding, rand)
+	}
+	if b.ake {
+		return Kex_ake_sharedB_rand(b.kexpp, recv, b.skb, b.pka, rand)
+	}
+	return Kex_uake_sharedB_rand(b.kexpp, recv, b.skb, rand)
+}
+
+/*************************************************
+* Name:        RespondConfirm
+*
+* Description: Respond for a confirmed session: returns the answer
+*              and the tag of B, keeping the key until Confirm checks
+*              the tag of A
+*
+* Arguments:   - recv []byte: message of A
+*
+* Returns      - send []byte: output message, as for Respond
+*              - tagB []byte: output tag of KEX_CONFIRMBYTES bytes
+*              - err error: as for Respond
+**************************************************/
+func (b *Responder) RespondConfirm(recv []byte) ([]byte, []byte, error) {
+	return b.RespondConfirmRand(recv, rand.Reader)
+}
+
+// RespondConfirmRand is RespondConfirm drawing its randomness from rand
+func (b *Responder) RespondConfirmRand(recv []byte, rand io.Reader) ([]byte, []byte, error) {
+	send, k, err := b.RespondRand(recv, rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	ck, tagA, tagB := Kex_confirm(k, recv, send)
+	zeroBytes(k)
+	b.ck, b.tagA = ck, tagA
+	b.state = kexStateSent
+	return send, tagB, nil
+}
+
+/*************************************************
+* Name:        Confirm
+*
+* Description: Checks the tag of A (third flight) and returns the
+*              confirmed session key
+*
+* Arguments:   - tagA []byte: confirmation tag of A
+*
+* Returns      - ck []byte: output confirmed key of KEX_SSBYTES bytes
+*              - err error: ErrKexState if RespondConfirm was not
+*                called, ErrKexConfirmation if tagA does not check
+**************************************************/
+func (b *Responder) Confirm(tagA []byte) ([]byte, error) {
+	if b.state != kexStateSent {
+		return nil, ErrKexState
+	}
+	b.state = kexStateDone
+	ck, want := b.ck, b.tagA
+	b.ck, b.tagA = nil, nil
+	if err := Kex_confirm_verify(tagA, want); err != nil {
+		zeroBytes(ck)
+		return nil, err
+	}
+	return ck, nil
+}
@@ -0,0 +1,198 @@
+package kyber
+
+import (
+	"bytes"
+	"testing"
+)
+
+func Test_Session(t *testing.T) {
+	for k := 2; k <= 4; k++ {
+		kexpp := NewKexParameters(k)
+		pkb, skb := Crypto_kem_keypair(kexpp.KemParams)
+		pka, ska := Crypto_kem_keypair(kexpp.KemParams)
+
+		for _, ake := range []bool{false, true} {
+			var a *Initiator
+			var b *Responder
+			var err error
+			if ake {
+				a, _ = NewAKEInitiator(kexpp, pkb, ska)
+				b, err = NewAKEResponder(kexpp, skb, pka)
+			} else {
+				a, _ = NewUAKEInitiator(kexpp, pkb)
+				b, err = NewUAKEResponder(kexpp, skb)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := a.Finish(nil); err != ErrKexState {
+				t.Errorf("Finish before Start: got %v", err)
+			}
+			senda, err := a.StartRand(newTestRand("alice"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := a.Start(); err != ErrKexState {
+				t.Errorf("second Start: got %v", err)
+			}
+			sendb, kb, err := b.RespondRand(senda, newTestRand("bob"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := b.Respond(senda); err != ErrKexState {
+				t.Errorf("second Respond: got %v", err)
+			}
+			esk, tk := a.esk, a.tk
+			ka, err := a.Finish(sendb)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(ka, kb) {
+				t.Errorf("k=%d ake=%v: keys differ", k, ake)
+			}
+			if !bytes.Equal(esk, make([]byte, len(esk))) || !bytes.Equal(tk, make([]byte, len(tk))) || a.esk != nil {
+				t.Errorf("k=%d ake=%v: ephemeral secrets not zeroed", k, ake)
+			}
+			if _, err := a.Finish(sendb); err != ErrKexState {
+				t.Errorf("second Finish: got %v", err)
+			}
+
+			// the sessions run the same flow as the Kex_* functions
+			var want []byte
+			if ake {
+				s, tk, esk, _ := Kex_ake_initA_rand(kexpp, pkb, newTestRand("alice"))
+				r, _, _ := Kex_ake_sharedB_rand(kexpp, s, skb, pka, newTestRand("bob"))
+				want = Kex_ake_sharedA(kexpp, r, tk, esk, ska)
+			} else {
+				s, tk, esk, _ := Kex_uake_initA_rand(kexpp, pkb, newTestRand("alice"))
+				r, _, _ := Kex_uake_sharedB_rand(kexpp, s, skb, newTestRand("bob"))
+				want = Kex_uake_sharedA(kexpp, r, tk, esk)
+			}
+			if !bytes.Equal(ka, want) {
+				t.Errorf("k=%d ake=%v: session key differs from Kex_* functions", k, ake)
+			}
+		}
+	}
+}
+
+// Test_Session_MLKEM runs the sessions over the ML-KEM parameter sets of
+// NewMLKEMKexParameters against the Kex_* functions
+func Test_Session_MLKEM(t *testing.T) {
+	for k := 2; k <= 4; k++ {
+		kexpp := NewMLKEMKexParameters(k)
+		if kexpp.KemParams.KYBER_MODE != KYBER_MODE_MLKEM {
+			t.Fatalf("k=%d: not an ML-KEM parameter set", k)
+		}
+		pkb, skb := Crypto_kem_keypair(kexpp.KemParams)
+		pka, ska := Crypto_kem_keypair(kexpp.KemParams)
+
+		for _, ake := range []bool{false, true} {
+			var a *Initiator
+			var b *Responder
+			var err error
+			if ake {
+				a, _ = NewAKEInitiator(kexpp, pkb, ska)
+				b, err = NewAKEResponder(kexpp, skb, pka)
+			} else {
+				a, _ = NewUAKEInitiator(kexpp, pkb)
+				b, err = NewUAKEResponder(kexpp, skb)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			senda, err := a.StartRand(newTestRand("alice"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sendb, kb, err := b.RespondRand(senda, newTestRand("bob"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ka, err := a.Finish(sendb)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(ka, kb) {
+				t.Errorf("%s ake=%v: keys differ", kexpp.KemParams.KYBER_NAME, ake)
+			}
+
+			var want []byte
+			if ake {
+				s, tk, esk, _ := Kex_ake_initA_rand(kexpp, pkb, newTestRand("alice"))
+				r, _, _ := Kex_ake_sharedB_rand(kexpp, s, skb, pka, newTestRand("bob"))
+				want = Kex_ake_sharedA(kexpp, r, tk, esk, ska)
+			} else {
+				s, tk, esk, _ := Kex_uake_initA_rand(kexpp, pkb, newTestRand("alice"))
+				r, _, _ := Kex_uake_sharedB_rand(kexpp, s, skb, newTestRand("bob"))
+				want = Kex_uake_sharedA(kexpp, r, tk, esk)
+			}
+			if !bytes.Equal(ka, want) {
+				t.Errorf("%s ake=%v: session key differs from Kex_* functions", kexpp.KemParams.KYBER_NAME, ake)
+			}
+		}
+	}
+}
+
+func Test_SessionErrors(t *testing.T) {
+	kexpp := NewKexParameters(3)
+	pkb, skb := Crypto_kem_keypair(kexpp.KemParams)
+	pka, ska := Crypto_kem_keypair(kexpp.KemParams)
+
+	if _, err := NewUAKEInitiator(kexpp, pkb[1:]); err != ErrInvalidPublicKeyLength {
+		t.Errorf("short pkb: got %v", err)
+	}
+	if _, err := NewAKEInitiator(kexpp, pkb, ska[1:]); err != ErrInvalidSecretKeyLength {
+		t.Errorf("short ska: got %v", err)
+	}
+	if _, err := NewUAKEResponder(kexpp, skb[1:]); err != ErrInvalidSecretKeyLength {
+		t.Errorf("short skb: got %v", err)
+	}
+	if _, err := NewAKEResponder(kexpp, skb, pka[1:]); err != ErrInvalidPublicKeyLength {
+		t.Errorf("short pka: got %v", err)
+	}
+
+	a, _ := NewAKEInitiator(kexpp, pkb, ska)
+	if _, err := a.StartRand(failingReader{}); err == nil {
+		t.Error("StartRand ignored RNG failure")
+	}
+	senda, err := a.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewAKEResponder(kexpp, skb, pka)
+	if _, _, err := b.Respond(senda[1:]); err != ErrInvalidKexMessageLength {
+		t.Errorf("short message to responder: got %v", err)
+	}
+	if _, _, err := b.Respond(senda); err != ErrKexState {
+		t.Errorf("responder reused after failure: got %v", err)
+	}
+
+	// a UAKE answer is too short for an AKE initiator, which then aborts
+	u, _ := NewUAKEResponder(kexpp, skb)
+	sendb, _, _ := u.Respond(senda)
+	if _, err := a.Finish(sendb); err != ErrInvalidKexMessageLength {
+		t.Errorf("UAKE answer to AKE initiator: got %v", err)
+	}
+	if a.esk != nil {
+		t.Error("ephemeral secret kept after failure")
+	}
+	if _, err := a.Finish(make([]byte, kexpp.KEX_AKE_SENDBBYTES)); err != ErrKexState {
+		t.Errorf("Finish after failure: got %v", err)
+	}
+
+	// an aborted session zeroes its secrets and ends
+	a, _ = NewAKEInitiator(kexpp, pkb, ska)
+	if _, err := a.Start(); err != nil {
+		t.Fatal(err)
+	}
+	esk, tk := a.esk, a.tk
+	a.Abort()
+	if !bytes.Equal(esk, make([]byte, len(esk))) || !bytes.Equal(tk, make([]byte, len(tk))) {
+		t.Error("ephemeral secrets not zeroed by Abort")
+	}
+	if _, err := a.Finish(sendb); err != ErrKexState {
+		t.Errorf("Finish after Abort: got %v", err)
+	}
+}
@@ -0,0 +1,264 @@
+// Package stream encrypts streams of any length to a Kyber public key.
+//
+// The stream starts with a header version (1) || KYBER_ID (1) || KEM
+// cipher text. The payload key is HKDF-SHA256(ss, salt = KEM cipher text,
+// info = label || header), and the payload is cut in ChunkSize chunks
+// sealed with ChaCha20-Poly1305 under the STREAM construction: the nonce
+// is an 11-byte big-endian chunk counter followed by a byte that is 1 for
+// the final chunk and 0 otherwise. Only the final chunk may be shorter
+// than ChunkSize, and it is empty only for an empty stream, so truncation,
+// reordering and appended data are all detected.
+package stream
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	kyber "github.com/depressi0n/kyber-go"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	Version     = 1
+	ChunkSize   = 64 * 1024
+	headerBytes = 2
+	tagSize     = chacha20poly1305.Overhead
+	encChunk    = ChunkSize + tagSize
+
+	streamLabel = "kyber-go stream"
+)
+
+var (
+	ErrInvalidHeader      = errors.New("stream: invalid header")
+	ErrParametersMismatch = errors.New("stream: stream is for another parameter set")
+	ErrAuthentication     = errors.New("stream: chunk authentication failed")
+	ErrTruncated          = errors.New("stream: stream truncated")
+	ErrTrailingData       = errors.New("stream: trailing data after final chunk")
+	ErrClosed             = errors.New("stream: write to closed writer")
+	ErrCounterOverflow    = errors.New("stream: chunk counter overflow")
+)
+
+func newAEAD(ss, ct, header []byte) (cipher.AEAD, error) {
+	key := make([]byte, chacha20poly1305.KeySize)
+	info := append([]byte(streamLabel), header...)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ss, ct, info), key); err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.New(key)
+}
+
+func nonce(counter uint64, last bool) []byte {
+	n := make([]byte, chacha20poly1305.NonceSize)
+	binary.BigEndian.PutUint64(n[3:11], counter)
+	if last {
+		n[11] = 1
+	}
+	return n
+}
+
+type writer struct {
+	dst     io.Writer
+	aead    cipher.AEAD
+	buf     []byte
+	counter uint64
+	err     error
+}
+
+/*************************************************
+* Name:        NewWriter
+*
+* Description: Encapsulates to pub, writes the stream header to dst
+*              and returns a writer encrypting to dst. Close must be
+*              called to write the final chunk; it does not close dst
+*
+* Arguments:   - pub *kyber.PublicKey: recipient public key
+*              - dst io.Writer: destination of the encrypted stream
+*
+* Returns      - w io.WriteCloser: output plaintext writer
+*              - err error: non-nil on failure
+**************************************************/
+func NewWriter(pub *kyber.PublicKey, dst io.Writer) (io.WriteCloser, error) {
+	return NewWriterRand(pub, dst, rand.Reader)
+}
+
+// NewWriterRand is NewWriter drawing its randomness from rand
+func NewWriterRand(pub *kyber.PublicKey, dst io.Writer, rand io.Reader) (io.WriteCloser, error) {
+	ct, ss, err := pub.EncapsulateRand(rand)
+	if err != nil {
+		return nil, err
+	}
+	header := []byte{Version, pub.Parameters().KYBER_ID}
+	aead, err := newAEAD(ss, ct, header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dst.Write(append(header, ct...)); err != nil {
+		return nil, err
+	}
+	return &writer{dst: dst, aead: aead, buf: make([]byte, 0, encChunk)}, nil
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	total := len(p)
+	for len(p) > 0 {
+		// a full buffer is only flushed once more data shows it is not final
+		if len(w.buf) == ChunkSize {
+			if err := w.flush(false); err != nil {
+				w.err = err
+				return total - len(p), err
+			}
+		}
+		n := copy(w.buf[len(w.buf):ChunkSize], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+	}
+	return total, nil
+}
+
+func (w *writer) flush(last bool) error {
+	if w.counter == 1<<64-1 {
+		return ErrCounterOverflow
+	}
+	out := w.aead.Seal(w.buf[:0], nonce(w.counter, last), w.buf, nil)
+	if _, err := w.dst.Write(out); err != nil {
+		return err
+	}
+	w.counter++
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// Close writes the final chunk
+func (w *writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	err := w.flush(true)
+	w.err = ErrClosed
+	return err
+}
+
+type reader struct {
+	src     io.Reader
+	aead    cipher.AEAD
+	enc     []byte
+	plain   []byte // decrypted, not yet returned
+	counter uint64
+	done    bool
+	err     error
+}
+
+/*************************************************
+* Name:        NewReader
+*
+* Description: Reads the stream header from src and returns a reader
+*              of the decrypted stream. Plaintext is only returned
+*              once its chunk authenticates; the reader fails with
+*              ErrTruncated if src ends before the final chunk
+*
+* Arguments:   - priv *kyber.PrivateKey: recipient private key
+*              - src io.Reader: source of the encrypted stream
+*
+* Returns      - r io.Reader: output plaintext reader
+*              - err error: non-nil on an invalid header
+**************************************************/
+func NewReader(priv *kyber.PrivateKey, src io.Reader) (io.Reader, error) {
+	params := priv.Parameters()
+	header := make([]byte, headerBytes)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return nil, ErrInvalidHeader
+	}
+	if header[0] != Version {
+		return nil, ErrInvalidHeader
+	}
+	if header[1] != params.KYBER_ID {
+		return nil, ErrParametersMismatch
+	}
+	ct := make([]byte, params.KYBER_CIPHERTEXTBYTES)
+	if _, err := io.ReadFull(src, ct); err != nil {
+		return nil, ErrInvalidHeader
+	}
+	ss, err := priv.Decapsulate(ct)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(ss, ct, header)
+	if err != nil {
+		return nil, err
+	}
+	return &reader{src: src, aead: aead, enc: make([]byte, encChunk)}, nil
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.readChunk(); err != nil {
+			r.err = err
+		}
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+func (r *reader) readChunk() error {
+	if r.counter == 1<<64-1 {
+		return ErrCounterOverflow
+	}
+	n, err := io.ReadFull(r.src, r.enc)
+	switch {
+	case err == io.EOF:
+		return ErrTruncated
+	case err == io.ErrUnexpectedEOF:
+		// a short chunk can only be the final one
+		return r.open(r.enc[:n], true)
+	case err != nil:
+		return err
+	}
+
+	if r.open(r.enc, false) == nil {
+		return nil
+	}
+	if err := r.open(r.enc, true); err != nil {
+		return err
+	}
+	// a full final chunk must be followed by the end of the stream
+	var extra [1]byte
+	if n, _ := io.ReadFull(r.src, extra[:]); n != 0 {
+		return ErrTrailingData
+	}
+	return nil
+}
+
+func (r *reader) open(chunk []byte, last bool) error {
+	if len(chunk) < tagSize {
+		return ErrTruncated
+	}
+	pt, err := r.aead.Open(r.plain[:0], nonce(r.counter, last), chunk, nil)
+	if err != nil {
+		if last {
+			return ErrAuthentication
+		}
+		return err
+	}
+	if last && len(pt) == 0 && r.counter != 0 {
+		return ErrAuthentication
+	}
+	r.plain = pt
+	r.counter++
+	r.done = last
+	return nil
+}
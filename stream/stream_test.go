@@ -0,0 +1,152 @@
+package stream
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+
+	kyber "github.com/depressi0n/kyber-go"
+)
+
+func encrypt(t *testing.T, pub *kyber.PublicKey, msg []byte) []byte {
+	var buf bytes.Buffer
+	w, err := NewWriter(pub, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// uneven writes exercise the chunk buffering
+	for len(msg) > 0 {
+		n := 1000
+		if n > len(msg) {
+			n = len(msg)
+		}
+		if _, err := w.Write(msg[:n]); err != nil {
+			t.Fatal(err)
+		}
+		msg = msg[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("x")); err != ErrClosed {
+		t.Errorf("write after Close: got %v", err)
+	}
+	return buf.Bytes()
+}
+
+func decrypt(priv *kyber.PrivateKey, enc []byte) ([]byte, error) {
+	r, err := NewReader(priv, bytes.NewReader(enc))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestStream(t *testing.T) {
+	params := kyber.NewMLKEMParameters(3)
+	priv, err := kyber.GenerateKey(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{0, 1, ChunkSize - 1, ChunkSize, ChunkSize + 1, 3*ChunkSize + 17} {
+		msg := make([]byte, size)
+		rand.Read(msg)
+		enc := encrypt(t, priv.PublicKey(), msg)
+
+		chunks := (size + ChunkSize - 1) / ChunkSize
+		if chunks == 0 {
+			chunks = 1
+		}
+		if want := headerBytes + params.KYBER_CIPHERTEXTBYTES + size + chunks*tagSize; len(enc) != want {
+			t.Errorf("size %d: encrypted stream is %d bytes, want %d", size, len(enc), want)
+		}
+		got, err := decrypt(priv, enc)
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if !bytes.Equal(got, msg) {
+			t.Errorf("size %d: decrypted stream differs", size)
+		}
+	}
+}
+
+func TestStreamTampering(t *testing.T) {
+	params := kyber.NewParameters(2)
+	priv, _ := kyber.GenerateKey(params)
+	msg := make([]byte, 2*ChunkSize+100)
+	rand.Read(msg)
+	enc := encrypt(t, priv.PublicKey(), msg)
+	body := headerBytes + params.KYBER_CIPHERTEXTBYTES
+
+	// dropping the final chunk at a chunk boundary
+	if _, err := decrypt(priv, enc[:body+2*encChunk]); !errors.Is(err, ErrTruncated) {
+		t.Errorf("dropped final chunk: got %v", err)
+	}
+	// cutting into the final chunk
+	if _, err := decrypt(priv, enc[:len(enc)-1]); !errors.Is(err, ErrAuthentication) {
+		t.Errorf("cut final chunk: got %v", err)
+	}
+	// swapping the first two chunks
+	swapped := append([]byte{}, enc...)
+	copy(swapped[body:], enc[body+encChunk:body+2*encChunk])
+	copy(swapped[body+encChunk:], enc[body:body+encChunk])
+	if _, err := decrypt(priv, swapped); !errors.Is(err, ErrAuthentication) {
+		t.Errorf("reordered chunks: got %v", err)
+	}
+	// appending data after the final chunk
+	if _, err := decrypt(priv, append(append([]byte{}, enc...), 0)); err == nil {
+		t.Error("trailing data accepted")
+	}
+	// flipping a payload bit
+	flipped := append([]byte{}, enc...)
+	flipped[body+10] ^= 1
+	if _, err := decrypt(priv, flipped); !errors.Is(err, ErrAuthentication) {
+		t.Errorf("flipped bit: got %v", err)
+	}
+
+	// a full-size final chunk followed by more data
+	exact := encrypt(t, priv.PublicKey(), make([]byte, ChunkSize))
+	if _, err := decrypt(priv, append(exact, 0)); !errors.Is(err, ErrTrailingData) {
+		t.Errorf("data after full final chunk: got %v", err)
+	}
+
+	other, _ := kyber.GenerateKey(params)
+	if _, err := decrypt(other, enc); !errors.Is(err, ErrAuthentication) {
+		t.Errorf("wrong key: got %v", err)
+	}
+	mlkem, _ := kyber.GenerateKey(kyber.NewMLKEMParameters(2))
+	if _, err := decrypt(mlkem, enc); !errors.Is(err, ErrParametersMismatch) {
+		t.Errorf("wrong parameter set: got %v", err)
+	}
+	if _, err := decrypt(priv, enc[:10]); !errors.Is(err, ErrInvalidHeader) {
+		t.Errorf("short header: got %v", err)
+	}
+}
+
+func TestStreamPartialReads(t *testing.T) {
+	priv, _ := kyber.GenerateKey(kyber.NewMLKEMParameters(4))
+	msg := make([]byte, ChunkSize+5)
+	rand.Read(msg)
+	enc := encrypt(t, priv.PublicKey(), msg)
+	r, err := NewReader(priv, bytes.NewReader(enc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []byte
+	buf := make([]byte, 777)
+	for {
+		n, err := r.Read(buf)
+		got = append(got, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(got, msg) {
+		t.Error("decrypted stream differs")
+	}
+}
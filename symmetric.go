@@ -37,3 +37,10 @@ func prf(out []byte, outlen int, key []byte, nonce byte) {
 func kdf(out []byte, outlen int, in []byte, inlen int) {
 	sha3.ShakeSum256(out[:outlen], in[:inlen])
 }
+
+func rkprf(out []byte, key []byte, in []byte) { // J(z||c) implicit rejection key of FIPS 203
+	h := sha3.NewShake256()
+	h.Write(key[:KYBER_SYMBYTES])
+	h.Write(in)
+	h.Read(out[:KYBER_SSBYTES])
+}
@@ -0,0 +1,266 @@
+// Package xwing implements the X-Wing hybrid KEM
+// (draft-connolly-cfrg-xwing-kem), combining ML-KEM-768 with X25519.
+//
+// Private keys are the 32-byte seed, public keys are pkM||pkX,
+// ciphertexts are ctM||ctX and the shared secret is
+// SHA3-256(ssM || ssX || ctX || pkX || XWingLabel).
+package xwing
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"io"
+
+	kyber "github.com/depressi0n/kyber-go"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	SeedSize              = 32
+	EncapsulationSeedSize = 64
+	SharedKeySize         = 32
+	PublicKeySize         = 1184 + x25519Size
+	CiphertextSize        = 1088 + x25519Size
+
+	x25519Size = 32
+)
+
+// \./
+// /^\
+const xwingLabel = `\./` + `/^\`
+
+var (
+	ErrInvalidSeedLength       = errors.New("xwing: invalid seed length")
+	ErrInvalidPublicKeyLength  = errors.New("xwing: invalid public key length")
+	ErrInvalidCiphertextLength = errors.New("xwing: invalid ciphertext length")
+)
+
+var params = kyber.NewMLKEMParameters(3)
+
+// PublicKey is an X-Wing encapsulation key
+type PublicKey struct {
+	m *kyber.PublicKey
+	x *ecdh.PublicKey
+}
+
+// PrivateKey is an X-Wing decapsulation key
+type PrivateKey struct {
+	seed []byte
+	m    *kyber.PrivateKey
+	x    *ecdh.PrivateKey
+}
+
+/*************************************************
+* Name:        GenerateKey
+*
+* Description: Generates a fresh private key from a
+*              seed of SeedSize bytes read from rand
+*
+* Arguments:   - rand io.Reader: source of randomness,
+*                or nil for crypto/rand
+*
+* Returns      - key *PrivateKey: output private key
+*              - err error: non-nil if rand fails
+**************************************************/
+func GenerateKey(r io.Reader) (*PrivateKey, error) {
+	if r == nil {
+		r = rand.Reader
+	}
+	seed := make([]byte, SeedSize)
+	if _, err := io.ReadFull(r, seed); err != nil {
+		return nil, err
+	}
+	return NewPrivateKeyFromSeed(seed)
+}
+
+/*************************************************
+* Name:        NewPrivateKeyFromSeed
+*
+* Description: Deterministically expands a seed to a key pair:
+*              SHAKE256(seed) gives the ML-KEM-768 seed d||z
+*              followed by the X25519 private key
+*
+* Arguments:   - seed []byte: input seed (of length SeedSize bytes)
+*
+* Returns      - key *PrivateKey: output private key
+*              - err error: ErrInvalidSeedLength on bad input
+**************************************************/
+func NewPrivateKeyFromSeed(seed []byte) (*PrivateKey, error) {
+	if len(seed) != SeedSize {
+		return nil, ErrInvalidSeedLength
+	}
+	expanded := make([]byte, kyber.KYBER_SEEDBYTES+x25519Size)
+	sha3.ShakeSum256(expanded, seed)
+
+	m, err := kyber.NewPrivateKeyFromSeed(params, expanded[:kyber.KYBER_SEEDBYTES])
+	if err != nil {
+		return nil, err
+	}
+	x, err := ecdh.X25519().NewPrivateKey(expanded[kyber.KYBER_SEEDBYTES:])
+	if err != nil {
+		return nil, err
+	}
+	s := make([]byte, SeedSize)
+	copy(s, seed)
+	return &PrivateKey{seed: s, m: m, x: x}, nil
+}
+
+/*************************************************
+* Name:        ParsePublicKey
+*
+* Description: Parses a serialized public key pkM||pkX
+*
+* Arguments:   - b []byte: input public key
+*                (of length PublicKeySize bytes)
+*
+* Returns      - key *PublicKey: output public key
+*              - err error: non-nil on invalid input
+**************************************************/
+func ParsePublicKey(b []byte) (*PublicKey, error) {
+	if len(b) != PublicKeySize {
+		return nil, ErrInvalidPublicKeyLength
+	}
+	m, err := kyber.ParsePublicKey(params, b[:params.KYBER_PUBLICKEYBYTES])
+	if err != nil {
+		return nil, err
+	}
+	x, err := ecdh.X25519().NewPublicKey(b[params.KYBER_PUBLICKEYBYTES:])
+	if err != nil {
+		return nil, err
+	}
+	return &PublicKey{m: m, x: x}, nil
+}
+
+// Bytes returns the serialized public key pkM||pkX
+func (pub *PublicKey) Bytes() []byte {
+	return append(pub.m.Bytes(), pub.x.Bytes()...)
+}
+
+// Equal reports whether pub and x hold the same public key
+func (pub *PublicKey) Equal(x *PublicKey) bool {
+	return pub.m.Equal(x.m) && pub.x.Equal(x.x)
+}
+
+/*************************************************
+* Name:        (*PublicKey).Encapsulate
+*
+* Description: Generates cipher text and shared secret,
+*              reading EncapsulationSeedSize bytes from rand
+*
+* Arguments:   - rand io.Reader: source of randomness,
+*                or nil for crypto/rand
+*
+* Returns      - ct []byte: output cipher text
+*                (CiphertextSize bytes)
+*              - ss []byte: output shared secret
+*                (SharedKeySize bytes)
+*              - err error: non-nil on failure
+**************************************************/
+func (pub *PublicKey) Encapsulate(r io.Reader) ([]byte, []byte, error) {
+	if r == nil {
+		r = rand.Reader
+	}
+	eseed := make([]byte, EncapsulationSeedSize)
+	if _, err := io.ReadFull(r, eseed); err != nil {
+		return nil, nil, err
+	}
+	return pub.EncapsulateDerand(eseed)
+}
+
+/*************************************************
+* Name:        (*PublicKey).EncapsulateDerand
+*
+* Description: Deterministic encapsulation: the first half of
+*              eseed is the ML-KEM message, the second half the
+*              ephemeral X25519 private key
+*
+* Arguments:   - eseed []byte: input randomness
+*                (of length EncapsulationSeedSize bytes)
+*
+* Returns      - ct []byte: output cipher text
+*              - ss []byte: output shared secret
+*              - err error: non-nil on failure
+**************************************************/
+func (pub *PublicKey) EncapsulateDerand(eseed []byte) ([]byte, []byte, error) {
+	if len(eseed) != EncapsulationSeedSize {
+		return nil, nil, ErrInvalidSeedLength
+	}
+	ctM, ssM, err := kyber.EncapsulateDerand(params, pub.m.Bytes(), eseed[:32])
+	if err != nil {
+		return nil, nil, err
+	}
+	ek, err := ecdh.X25519().NewPrivateKey(eseed[32:])
+	if err != nil {
+		return nil, nil, err
+	}
+	ssX, err := ek.ECDH(pub.x)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctX := ek.PublicKey().Bytes()
+
+	ss := combiner(ssM, ssX, ctX, pub.x.Bytes())
+	return append(ctM, ctX...), ss, nil
+}
+
+// Bytes returns a copy of the private key seed
+func (priv *PrivateKey) Bytes() []byte {
+	seed := make([]byte, SeedSize)
+	copy(seed, priv.seed)
+	return seed
+}
+
+// PublicKey returns the public key of priv
+func (priv *PrivateKey) PublicKey() *PublicKey {
+	return &PublicKey{m: priv.m.PublicKey(), x: priv.x.PublicKey()}
+}
+
+// Equal reports whether priv and x hold the same private key
+func (priv *PrivateKey) Equal(x *PrivateKey) bool {
+	return subtle.ConstantTimeCompare(priv.seed, x.seed) == 1
+}
+
+/*************************************************
+* Name:        (*PrivateKey).Decapsulate
+*
+* Description: Generates shared secret for given cipher text.
+*              As with ML-KEM, an invalid ciphertext yields a
+*              pseudo-random shared secret rather than an error
+*
+* Arguments:   - ct []byte: input cipher text
+*                (of length CiphertextSize bytes)
+*
+* Returns      - ss []byte: output shared secret
+*              - err error: non-nil on malformed input
+**************************************************/
+func (priv *PrivateKey) Decapsulate(ct []byte) ([]byte, error) {
+	if len(ct) != CiphertextSize {
+		return nil, ErrInvalidCiphertextLength
+	}
+	ctM, ctX := ct[:params.KYBER_CIPHERTEXTBYTES], ct[params.KYBER_CIPHERTEXTBYTES:]
+	ssM, err := priv.m.Decapsulate(ctM)
+	if err != nil {
+		return nil, err
+	}
+	ek, err := ecdh.X25519().NewPublicKey(ctX)
+	if err != nil {
+		return nil, err
+	}
+	ssX, err := priv.x.ECDH(ek)
+	if err != nil {
+		return nil, err
+	}
+	return combiner(ssM, ssX, ctX, priv.x.PublicKey().Bytes()), nil
+}
+
+func combiner(ssM, ssX, ctX, pkX []byte) []byte {
+	h := sha3.New256()
+	h.Write(ssM)
+	h.Write(ssX)
+	h.Write(ctX)
+	h.Write(pkX)
+	h.Write([]byte(xwingLabel))
+	return h.Sum(nil)
+}
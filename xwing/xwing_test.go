@@ -0,0 +1,128 @@
+package xwing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// X-Wing vectors extracted from the draft-ietf-hpke-pq test data
+// of Go crypto/hpke (KEM 0x647a): seed is skRm, eseed is ikmE
+type vector struct {
+	Seed  string `json:"seed"`
+	Pk    string `json:"pk"`
+	Eseed string `json:"eseed"`
+	Ct    string `json:"ct"`
+	Ss    string `json:"ss"`
+}
+
+func mustDecode(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestVectors(t *testing.T) {
+	data, err := os.ReadFile("testdata/vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors []vector
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) == 0 {
+		t.Fatal("no vectors")
+	}
+
+	for i, v := range vectors {
+		priv, err := NewPrivateKeyFromSeed(mustDecode(t, v.Seed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(priv.PublicKey().Bytes(), mustDecode(t, v.Pk)) {
+			t.Errorf("vector %d: public key mismatch", i)
+		}
+		pub, err := ParsePublicKey(mustDecode(t, v.Pk))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ct, ss, err := pub.EncapsulateDerand(mustDecode(t, v.Eseed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ct, mustDecode(t, v.Ct)) {
+			t.Errorf("vector %d: ciphertext mismatch", i)
+		}
+		if !bytes.Equal(ss, mustDecode(t, v.Ss)) {
+			t.Errorf("vector %d: shared secret mismatch", i)
+		}
+		ss2, err := priv.Decapsulate(ct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ss2, ss) {
+			t.Errorf("vector %d: decapsulated shared secret mismatch", i)
+		}
+	}
+}
+
+func TestXWing(t *testing.T) {
+	priv, err := GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := priv.PublicKey()
+	if len(pub.Bytes()) != PublicKeySize {
+		t.Fatalf("public key is %d bytes", len(pub.Bytes()))
+	}
+	ct, ss, err := pub.Encapsulate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ct) != CiphertextSize || len(ss) != SharedKeySize {
+		t.Fatalf("got %d byte ciphertext, %d byte shared secret", len(ct), len(ss))
+	}
+	ss2, err := priv.Decapsulate(ct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ss, ss2) {
+		t.Error("shared secrets differ")
+	}
+
+	// tampering with either half changes the shared secret
+	for _, i := range []int{0, CiphertextSize - 1} {
+		bad := append([]byte{}, ct...)
+		bad[i] ^= 1
+		ss3, err := priv.Decapsulate(bad)
+		if err != nil {
+			continue
+		}
+		if bytes.Equal(ss, ss3) {
+			t.Errorf("tampered byte %d gave the same shared secret", i)
+		}
+	}
+
+	priv2, err := NewPrivateKeyFromSeed(priv.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !priv.Equal(priv2) || !pub.Equal(priv2.PublicKey()) {
+		t.Error("key regenerated from seed differs")
+	}
+
+	if _, err := NewPrivateKeyFromSeed(make([]byte, 31)); err != ErrInvalidSeedLength {
+		t.Errorf("short seed: got %v", err)
+	}
+	if _, err := ParsePublicKey(pub.Bytes()[1:]); err != ErrInvalidPublicKeyLength {
+		t.Errorf("short public key: got %v", err)
+	}
+	if _, err := priv.Decapsulate(ct[1:]); err != ErrInvalidCiphertextLength {
+		t.Errorf("short ciphertext: got %v", err)
+	}
+}